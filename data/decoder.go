@@ -270,7 +270,7 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 			for i := 0; i < fromLen; i++ {
 				v := to.Index(i)
 
-				if err := dec.decode(from.Index(i), v); err != nil {
+				if err := dec.decode(from.Index(i), v.Addr()); err != nil {
 					return err
 				}
 			}
@@ -288,7 +288,7 @@ func (dec *Decoder) decode(from reflect.Value, to reflect.Value) error {
 			for i := 0; i < fromLen; i++ {
 				v := val.Index(i)
 
-				if err := dec.decode(from.Index(i), v); err != nil {
+				if err := dec.decode(from.Index(i), v.Addr()); err != nil {
 					return err
 				}
 			}
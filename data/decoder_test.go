@@ -114,3 +114,21 @@ func TestDecode(t *testing.T) {
 		}
 	}
 }
+
+func TestDecodePointerSlice(t *testing.T) {
+	type item struct {
+		Name string
+	}
+
+	a := assert.New(t)
+	d := Make(RawData{
+		"Items": []any{nil, RawData{"Name": "a"}},
+	})
+	var v struct {
+		Items []*item
+	}
+
+	dec := &Decoder{}
+	a.NilError(dec.Decode(d, &v))
+	a.Equal(v.Items, []*item{nil, {Name: "a"}})
+}
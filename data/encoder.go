@@ -281,7 +281,11 @@ func (enc *Encoder) copySlice(slice reflect.Value) reflect.Value {
 
 		for i := 0; i < l; i++ {
 			val := enc.encodeMapValue(slice.Index(i))
-			copied.Index(i).Set(val)
+
+			// Keep nil elements as is.
+			if val.IsValid() {
+				copied.Index(i).Set(val)
+			}
 		}
 
 		return copied
@@ -44,3 +44,13 @@ func TestEncoder(t *testing.T) {
 		a.Equal(c.Data, enc.Encode(c.Value))
 	}
 }
+
+func TestEncoderNilSliceElement(t *testing.T) {
+	a := assert.New(t)
+	d := Make(RawData{
+		"list": []any{nil, "a"},
+	})
+
+	a.Equal(d.Get("list", "0"), nil)
+	a.Equal(d.Get("list", "1"), "a")
+}
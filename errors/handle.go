@@ -1,8 +1,11 @@
 package errors
 
-import "fmt"
+import "runtime"
 
 // Handle recovers from panic and returns error to the target if panic is an error.
+//
+// If panic is not an error or is a runtime error, the target is set to a PanicError
+// carrying the stack trace, which is also reported to the panic sink set by SetPanicSink.
 func Handle(target *error) {
 	r := recover()
 
@@ -11,9 +14,13 @@ func Handle(target *error) {
 	}
 
 	if err, ok := r.(error); ok {
-		*target = err
-		return
+		if _, ok := err.(runtime.Error); !ok {
+			*target = err
+			return
+		}
 	}
 
-	*target = New(fmt.Sprintf("errors: uncaught panic: %v", r))
+	pe := newPanicError(r, 1)
+	reportPanic(pe)
+	*target = pe
 }
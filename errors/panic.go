@@ -0,0 +1,122 @@
+package errors
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// PanicError is an error recovered from a panic which is not thrown as an error,
+// e.g. panic with a string or a runtime error like nil pointer dereference.
+// Such panics are usually bugs rather than business errors.
+type PanicError interface {
+	error
+
+	// Value returns the value passed to panic.
+	Value() any
+
+	// Frames returns stack frames from where the panic happens to the outermost caller.
+	Frames() []runtime.Frame
+
+	// Stack returns the formatted stack trace.
+	Stack() string
+}
+
+type panicError struct {
+	value  any
+	msg    string
+	frames []runtime.Frame
+}
+
+var _ PanicError = new(panicError)
+
+// newPanicError creates a PanicError with current stack.
+// It must be called by the deferred function recovering from panic.
+func newPanicError(value any, skip int) *panicError {
+	var pcs [64]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	pe := &panicError{
+		value: value,
+	}
+
+	if err, ok := value.(error); ok {
+		pe.msg = err.Error()
+	} else {
+		pe.msg = fmt.Sprintf("errors: uncaught panic: %v", value)
+	}
+
+	// Skip runtime frames of panic itself.
+	skipping := true
+
+	for {
+		frame, more := frames.Next()
+
+		if !skipping || !strings.HasPrefix(frame.Function, "runtime.") {
+			skipping = false
+			pe.frames = append(pe.frames, frame)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return pe
+}
+
+func (pe *panicError) Error() string {
+	return pe.msg
+}
+
+func (pe *panicError) Unwrap() error {
+	err, _ := pe.value.(error)
+	return err
+}
+
+func (pe *panicError) Value() any {
+	return pe.value
+}
+
+func (pe *panicError) Frames() []runtime.Frame {
+	return pe.frames
+}
+
+func (pe *panicError) Stack() string {
+	builder := &strings.Builder{}
+
+	for _, frame := range pe.frames {
+		fmt.Fprintf(builder, "%v\n\t%v:%v\n", frame.Function, frame.File, frame.Line)
+	}
+
+	return builder.String()
+}
+
+// PanicSink receives every PanicError created by Handle.
+type PanicSink func(err PanicError)
+
+var panicSink atomic.Pointer[PanicSink]
+
+func init() {
+	SetPanicSink(defaultPanicSink)
+}
+
+// SetPanicSink sets the sink receiving every PanicError created by Handle.
+// The default sink prints the panic and its stack to stderr.
+// If sink is nil, panics are not reported.
+func SetPanicSink(sink PanicSink) {
+	panicSink.Store(&sink)
+}
+
+func reportPanic(err PanicError) {
+	if sink := *panicSink.Load(); sink != nil {
+		sink(err)
+	}
+}
+
+func defaultPanicSink(err PanicError) {
+	// TODO: use logger instead of fmt.
+	fmt.Fprintf(os.Stderr, "%v\n%v", err.Error(), err.Stack())
+}
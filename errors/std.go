@@ -37,12 +37,12 @@ func Is(err, target error) bool {
 		}
 	}
 
-	if unwrap, ok := target.(errorUnwrap); ok {
+	if unwrap, ok := err.(errorUnwrap); ok {
 		unwrapped := unwrap.Unwrap()
 		return Is(unwrapped, target)
 	}
 
-	if unwrap, ok := target.(errorUnwrapAll); ok {
+	if unwrap, ok := err.(errorUnwrapAll); ok {
 		unwrapped := unwrap.Unwrap()
 
 		for _, e := range unwrapped {
@@ -0,0 +1,30 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestIs(t *testing.T) {
+	a := assert.New(t)
+	target := New("target")
+	other := New("other")
+
+	a.Assert(Is(target, target))
+	a.Assert(!Is(other, target))
+	a.Assert(Is(nil, nil))
+	a.Assert(!Is(target, nil))
+
+	// Errors wrapped by err are matched.
+	a.Assert(Is(fmt.Errorf("wrapped: %w", target), target))
+	a.Assert(Is(fmt.Errorf("wrapped: %w", fmt.Errorf("wrapped: %w", target)), target))
+	a.Assert(Is(Join(other, target), target))
+	a.Assert(Is(Join(other, fmt.Errorf("wrapped: %w", target)), target))
+
+	// Errors wrapped by target are not matched.
+	a.Assert(!Is(target, fmt.Errorf("wrapped: %w", target)))
+	a.Assert(!Is(target, Join(other, target)))
+	a.Assert(!Is(Join(other), target))
+}
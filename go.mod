@@ -4,9 +4,12 @@ go 1.18
 
 require (
 	github.com/bytedance/sonic v1.8.1
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/huandu/go-assert v1.1.5
 	github.com/huandu/go-clone v1.5.0
 	github.com/huandu/xstrings v1.4.0
+	github.com/klauspost/compress v1.16.7
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -15,5 +18,7 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/arch v0.0.0-20210923205945-b76863e36670 // indirect
 )
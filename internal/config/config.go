@@ -1,7 +1,9 @@
 package config
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"os"
 
 	"github.com/go-shana/core/data"
@@ -28,13 +30,26 @@ func (c *Config) Load(ctx context.Context, filename string) (err error) {
 	file := errors.Check1(os.Open(filename))
 	defer file.Close()
 
-	decoder := yaml.NewDecoder(file)
+	c.load(file)
+	return
+}
+
+// LoadBytes parses config content in YAML and merges parsed data with existing data.
+func (c *Config) LoadBytes(ctx context.Context, content []byte) (err error) {
+	defer errors.Handle(&err)
+
+	c.load(bytes.NewReader(content))
+	return
+}
+
+func (c *Config) load(r io.Reader) {
+	decoder := yaml.NewDecoder(r)
 	raw := data.RawData{}
+
 	errInvalidConfigFile.Check(decoder.Decode(&raw))
+
 	d := data.Make(raw)
 	data.MergeTo(&c.data, d)
-
-	return
 }
 
 // Data returns parsed data.
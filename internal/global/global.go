@@ -1,10 +1,18 @@
 package global
 
-import "github.com/go-shana/core/config"
+import (
+	"time"
+
+	"github.com/go-shana/core/config"
+)
 
 // Config is the configuration for global microservice.
 type Config struct {
 	Debug bool `shana:"debug"`
+
+	// ShutdownDelay is the time to wait after the service becomes unready and before servers shut down,
+	// so that load balancers have a chance to stop sending new requests.
+	ShutdownDelay time.Duration `shana:"shutdown_delay"`
 }
 
 var (
@@ -15,3 +23,8 @@ var (
 func Debug() bool {
 	return defaultConfig.Debug
 }
+
+// ShutdownDelay returns the delay before shutting down servers.
+func ShutdownDelay() time.Duration {
+	return defaultConfig.ShutdownDelay
+}
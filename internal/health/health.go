@@ -0,0 +1,83 @@
+// Package health keeps the health state of the service.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-shana/core/errors"
+)
+
+// Func checks whether a dependency is healthy.
+type Func func(ctx context.Context) error
+
+type check struct {
+	Name string
+	Func Func
+}
+
+// Result is the result of a health check.
+type Result struct {
+	Name string
+	Err  error
+}
+
+var (
+	ready atomic.Bool
+
+	mu     sync.RWMutex
+	checks []check
+)
+
+// SetReady sets whether the service is ready to serve requests.
+func SetReady(r bool) {
+	ready.Store(r)
+}
+
+// Ready returns true if the service is ready to serve requests.
+func Ready() bool {
+	return ready.Load()
+}
+
+// AddCheck adds a named health check.
+func AddCheck(name string, f Func) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	checks = append(checks, check{
+		Name: name,
+		Func: f,
+	})
+}
+
+// Check runs all health checks in the order they are added.
+// It returns results of all checks and true if all checks pass.
+func Check(ctx context.Context) (results []Result, healthy bool) {
+	mu.RLock()
+	cs := checks
+	mu.RUnlock()
+
+	healthy = true
+	results = make([]Result, 0, len(cs))
+
+	for _, c := range cs {
+		err := c.run(ctx)
+
+		if err != nil {
+			healthy = false
+		}
+
+		results = append(results, Result{
+			Name: c.Name,
+			Err:  err,
+		})
+	}
+
+	return
+}
+
+func (c *check) run(ctx context.Context) (err error) {
+	defer errors.Handle(&err)
+	return c.Func(ctx)
+}
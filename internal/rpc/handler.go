@@ -11,5 +11,27 @@ type Handler struct {
 	// Func must be a function with signature:
 	//
 	//	func(ctx context.Context, req *Request) (resp *Response, err error)
+	//
+	// If StreamItem is not nil, Func must be a function with signature:
+	//
+	//	func(ctx context.Context, req *Request, send SendFunc) error
 	Func reflect.Value
+
+	// StreamItem is the type of items sent by a streaming handler.
+	// It's nil if the handler is not a streaming handler.
+	StreamItem reflect.Type
+
+	HTTP   HTTPRoute // Customized HTTP route.
+	Public bool      // Public handler doesn't require authentication.
 }
+
+// HTTPRoute customizes how a handler is served in HTTP.
+type HTTPRoute struct {
+	Method string // HTTP method. If it's empty, both GET and POST are allowed.
+	Path   string // Path template, e.g. "/users/{id}". If it's empty, the path is derived from package and name.
+	Status int    // Status code of successful response. If it's 0, 200 is used.
+}
+
+// SendFunc sends an item to client in a streaming handler.
+// The item is always a pointer to the StreamItem.
+type SendFunc func(item any) error
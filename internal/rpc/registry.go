@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -20,13 +21,22 @@ func DefaultRegistry() *Registry {
 }
 
 // Register registers a RPC handler.
-func Register(handler *Handler) {
-	defaultRegistry.Register(handler)
+func Register(handler *Handler) error {
+	return defaultRegistry.Register(handler)
 }
 
 // Register registers a RPC handler.
-func (r *Registry) Register(handler *Handler) {
+// It returns error if there is a handler with the same name in the same package.
+func (r *Registry) Register(handler *Handler) error {
+	for _, h := range r.packages[handler.Package] {
+		if h.Name == handler.Name {
+			return fmt.Errorf("rpc: duplicated handler name in package [package=%v] [name=%v] [funcs=%v, %v]",
+				handler.Package, handler.Name, h.FuncName, handler.FuncName)
+		}
+	}
+
 	r.packages[handler.Package] = append(r.packages[handler.Package], handler)
+	return nil
 }
 
 // Handlers returns all registered handlers under a package.
@@ -0,0 +1,27 @@
+package rpc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestRegistryRegister(t *testing.T) {
+	a := assert.New(t)
+	registry := &Registry{
+		packages: PackageMap{},
+	}
+
+	a.NilError(registry.Register(&Handler{Package: "a/b", Name: "foo", FuncName: "Foo"}))
+	a.NilError(registry.Register(&Handler{Package: "a/b", Name: "bar", FuncName: "Bar"}))
+	a.NilError(registry.Register(&Handler{Package: "a/c", Name: "foo", FuncName: "Foo"}))
+
+	err := registry.Register(&Handler{Package: "a/b", Name: "foo", FuncName: "FOO"})
+	a.NonNilError(err)
+	a.Assert(strings.Contains(err.Error(), "Foo, FOO"))
+
+	a.Equal(len(registry.Handlers("a/b")), 2)
+	a.Equal(len(registry.Handlers("a")), 3)
+	a.Equal(len(registry.Handlers("a/bc")), 0)
+}
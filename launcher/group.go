@@ -0,0 +1,68 @@
+package launcher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-shana/core/rpc"
+)
+
+// serverGroup runs several servers as one rpc.Server.
+//
+// All servers serve concurrently. Once any server stops, e.g. fails to listen,
+// the group shuts down all servers.
+// Servers are shut down one by one in the order they're created.
+type serverGroup struct {
+	servers []rpc.Server
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+var _ rpc.Server = new(serverGroup)
+
+func newServerGroup(servers []rpc.Server) *serverGroup {
+	return &serverGroup{
+		servers: servers,
+	}
+}
+
+// Serve starts all servers and waits for all of them to stop.
+// It returns the first error returned by servers.
+func (g *serverGroup) Serve(ctx context.Context) (err error) {
+	errs := make(chan error, len(g.servers))
+
+	for _, server := range g.servers {
+		go func(server rpc.Server) {
+			errs <- server.Serve(ctx)
+		}(server)
+	}
+
+	for range g.servers {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+
+		// Shutdown is blocking and other servers must be drained in the meantime.
+		go g.Shutdown(ctx)
+	}
+
+	// Wait for shutdown to finish.
+	g.Shutdown(ctx)
+	return
+}
+
+// Shutdown shuts down all servers in order.
+// It's safe to call Shutdown concurrently and all calls return after shutdown is done.
+// The first error returned by servers is returned.
+func (g *serverGroup) Shutdown(ctx context.Context) error {
+	g.shutdownOnce.Do(func() {
+		for _, server := range g.servers {
+			if err := server.Shutdown(ctx); err != nil && g.shutdownErr == nil {
+				g.shutdownErr = err
+			}
+		}
+	})
+
+	return g.shutdownErr
+}
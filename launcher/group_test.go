@@ -0,0 +1,100 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testServer struct {
+	name     string
+	serveErr error
+	stopped  chan struct{}
+	stopOnce sync.Once
+	record   func(name string)
+}
+
+func newTestServer(name string, serveErr error, record func(name string)) *testServer {
+	return &testServer{
+		name:     name,
+		serveErr: serveErr,
+		stopped:  make(chan struct{}),
+		record:   record,
+	}
+}
+
+func (s *testServer) Serve(ctx context.Context) error {
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+
+	<-s.stopped
+	return nil
+}
+
+func (s *testServer) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		s.record(s.name)
+		close(s.stopped)
+	})
+	return nil
+}
+
+func TestServerGroup(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	mu := &sync.Mutex{}
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	// Shutdown stops all servers in order.
+	group := newServerGroup([]rpc.Server{
+		newTestServer("public", nil, record),
+		newTestServer("admin", nil, record),
+	})
+	done := make(chan error)
+	go func() {
+		done <- group.Serve(ctx)
+	}()
+	a.NilError(group.Shutdown(ctx))
+	a.NilError(<-done)
+	a.Equal(order, []string{"public", "admin"})
+
+	// A failed server shuts down others.
+	order = nil
+	errListen := errors.New("fail to listen")
+	group = newServerGroup([]rpc.Server{
+		newTestServer("public", nil, record),
+		newTestServer("admin", errListen, record),
+		newTestServer("debug", nil, record),
+	})
+	a.Equal(group.Serve(ctx), errListen)
+	a.Equal(order, []string{"public", "admin", "debug"})
+}
+
+func TestNewServer(t *testing.T) {
+	a := assert.New(t)
+	record := func(string) {}
+	s1 := newTestServer("s1", nil, record)
+	s2 := newTestServer("s2", nil, record)
+
+	a.Equal(newServer(nil), nil)
+	a.Equal(newServer([]func() rpc.Server{nil, func() rpc.Server { return nil }}), nil)
+	a.Equal(newServer([]func() rpc.Server{func() rpc.Server { return s1 }}), s1)
+
+	server := newServer([]func() rpc.Server{
+		func() rpc.Server { return s1 },
+		func() rpc.Server { return s2 },
+	})
+	group, ok := server.(*serverGroup)
+	a.Assert(ok)
+	a.Equal(group.servers, []rpc.Server{s1, s2})
+}
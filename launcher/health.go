@@ -0,0 +1,20 @@
+package launcher
+
+import (
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/internal/health"
+)
+
+var errInvalidHealthCheck = errors.New("invalid health check")
+
+// AddHealthCheck registers f as a health check named name.
+// All health checks are called when the health or readiness of the service is queried,
+// e.g. through "/_shana/healthz" and "/_shana/readyz" in HTTP JSON server.
+func AddHealthCheck(name string, f Func) {
+	if name == "" || f == nil {
+		errors.Throw(errInvalidHealthCheck)
+		return
+	}
+
+	health.AddCheck(name, health.Func(f))
+}
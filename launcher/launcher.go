@@ -7,9 +7,12 @@ import (
 	"os/signal"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/go-shana/core/errors"
 	"github.com/go-shana/core/internal/config"
+	"github.com/go-shana/core/internal/global"
+	"github.com/go-shana/core/internal/health"
 	"github.com/go-shana/core/internal/lifecycle"
 	"github.com/go-shana/core/rpc"
 )
@@ -17,6 +20,16 @@ import (
 // Launch is the entry point for a service.
 // This function should be called from the main generated by shana toolchain.
 func Launch(createServer func() rpc.Server) (err error) {
+	return LaunchAll(createServer)
+}
+
+// LaunchAll is the entry point for a service serving several servers,
+// e.g. a public HTTP JSON server and an internal admin server.
+//
+// Servers are created in order before any of them starts serving.
+// If any server stops, all other servers are shut down as well.
+// On shutdown, servers are shut down one by one in the order of createServers.
+func LaunchAll(createServers ...func() rpc.Server) (err error) {
 	checkLaunched()
 
 	defer func() {
@@ -61,8 +74,9 @@ func Launch(createServer func() rpc.Server) (err error) {
 	lifecycle.OnConnect.Reset()
 	lifecycle.OnStart.Reset()
 
-	// Start server.
-	server := createServer()
+	// Start servers.
+	server := newServer(createServers)
+	health.SetReady(true)
 	errors.Check(startServer(ctx, server))
 
 	// Run service shutdown handlers.
@@ -89,6 +103,31 @@ func isFileExists(filename string) bool {
 	return true
 }
 
+// newServer creates all servers and combines them into one server.
+// It returns nil if there is no server.
+func newServer(createServers []func() rpc.Server) rpc.Server {
+	servers := make([]rpc.Server, 0, len(createServers))
+
+	for _, create := range createServers {
+		if create == nil {
+			continue
+		}
+
+		if server := create(); server != nil {
+			servers = append(servers, server)
+		}
+	}
+
+	switch len(servers) {
+	case 0:
+		return nil
+	case 1:
+		return servers[0]
+	}
+
+	return newServerGroup(servers)
+}
+
 func startServer(ctx context.Context, server rpc.Server) (err error) {
 	if server == nil {
 		return
@@ -106,6 +145,13 @@ func startServer(ctx context.Context, server rpc.Server) (err error) {
 
 		select {
 		case <-c:
+			// Stop accepting new requests from load balancers before shutting down.
+			health.SetReady(false)
+
+			if delay := global.ShutdownDelay(); delay > 0 {
+				time.Sleep(delay)
+			}
+
 			errChan <- server.Shutdown(ctx)
 		case <-exitChan:
 			errChan <- nil
@@ -114,6 +160,7 @@ func startServer(ctx context.Context, server rpc.Server) (err error) {
 		signal.Stop(c)
 	}()
 
+	defer health.SetReady(false)
 	errors.Check(server.Serve(ctx))
 	close(exitChan)
 	err = <-errChan
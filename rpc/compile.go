@@ -2,24 +2,74 @@ package rpc
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
 	"github.com/go-shana/core/errors"
 	"github.com/go-shana/core/initer"
 	"github.com/go-shana/core/validator"
+	"github.com/huandu/xstrings"
 )
 
-// Compile wraps a method with request validator, initer and error handler.
+// ErrInvalidRequest is joined with errors returned by request validators and initers.
+// Servers can check it with errors.Is to tell client errors from server errors.
+var ErrInvalidRequest = errors.New("rpc: invalid request")
+
+// Compile wraps a method with interceptors, request validator, initer and error handler.
 // The returned method is used in RPC server to handle any request.
 //
 // Business code should never call Compile unless for test purpose, e.g.
 // writing unit test cases for a RPC method.
-func Compile[Request, Response any](method HandlerFunc[Request, Response]) HandlerFunc[Request, Response] {
+func Compile[Request, Response any](method HandlerFunc[Request, Response], opts ...Option) HandlerFunc[Request, Response] {
+	info := parseFuncInfo(reflect.ValueOf(method))
+	return compile(&HandlerInfo{
+		Package:  info.Package,
+		Name:     xstrings.ToKebabCase(info.Name),
+		FuncName: info.Name,
+	}, method, newOptions(opts))
+}
+
+func compile[Request, Response any](info *HandlerInfo, method HandlerFunc[Request, Response], opts *options) HandlerFunc[Request, Response] {
+	interceptors := opts.interceptors
+	handler := func(ctx context.Context, req any) (resp any, err error) {
+		r, ok := req.(*Request)
+
+		if !ok {
+			return nil, fmt.Errorf("rpc: invalid request type [expected=%T] [actual=%T]", r, req)
+		}
+
+		checkRequest(ctx, r)
+		return method(ctx, r)
+	}
+
 	return func(ctx context.Context, req *Request) (resp *Response, err error) {
 		defer errors.Handle(&err)
 
-		validator.Validate(ctx, req)
-		initer.Init(ctx, req)
-		resp, err = method(ctx, req)
+		ret, err := intercept(ctx, info, req, globalInterceptors, func(ctx context.Context, req any) (resp any, err error) {
+			return intercept(ctx, info, req, interceptors, handler)
+		})
+
+		if ret == nil {
+			return
+		}
+
+		resp, ok := ret.(*Response)
+
+		if !ok {
+			errors.Throwf("rpc: invalid response type [expected=%T] [actual=%T]", resp, ret)
+		}
+
 		return
 	}
 }
+
+// checkRequest validates and initializes req.
+func checkRequest(ctx context.Context, req any) {
+	if err := validator.Validate(ctx, req); err != nil {
+		errors.Throw(err, ErrInvalidRequest)
+	}
+
+	if err := initer.Init(ctx, req); err != nil {
+		errors.Throw(err, ErrInvalidRequest)
+	}
+}
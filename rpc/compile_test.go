@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/huandu/go-assert"
+)
+
+type testCompileRequest struct {
+	Value int
+}
+
+func (req *testCompileRequest) Validate(ctx context.Context) {
+	if req.Value < 0 {
+		errors.Throwf("negative value [value=%v]", req.Value)
+	}
+}
+
+type testCompileResponse struct {
+	Value int
+}
+
+func testCompileMethod(ctx context.Context, req *testCompileRequest) (resp *testCompileResponse, err error) {
+	resp = &testCompileResponse{
+		Value: req.Value,
+	}
+	return
+}
+
+func testTraceInterceptor(name string, trace *[]string) Interceptor {
+	return func(ctx context.Context, info *HandlerInfo, req any, next Invoker) (resp any, err error) {
+		*trace = append(*trace, name+":"+info.Name)
+		return next(ctx, req)
+	}
+}
+
+func TestCompileInterceptors(t *testing.T) {
+	a := assert.New(t)
+	saved := globalInterceptors
+	defer func() {
+		globalInterceptors = saved
+	}()
+
+	var trace []string
+	globalInterceptors = nil
+	Use(testTraceInterceptor("global1", &trace), testTraceInterceptor("global2", &trace))
+	method := Compile(testCompileMethod, WithInterceptors(testTraceInterceptor("local", &trace)))
+	ctx := context.Background()
+
+	resp, err := method(ctx, &testCompileRequest{Value: 1})
+	a.NilError(err)
+	a.Equal(resp.Value, 1)
+	a.Equal(trace, []string{
+		"global1:test-compile-method",
+		"global2:test-compile-method",
+		"local:test-compile-method",
+	})
+
+	// Validator runs after all interceptors.
+	trace = nil
+	_, err = method(ctx, &testCompileRequest{Value: -1})
+	a.NonNilError(err)
+	a.Equal(len(trace), 3)
+}
+
+func TestCompileShortCircuit(t *testing.T) {
+	a := assert.New(t)
+	errDenied := errors.New("denied")
+	method := Compile(testCompileMethod, WithInterceptors(func(ctx context.Context, info *HandlerInfo, req any, next Invoker) (resp any, err error) {
+		if req.(*testCompileRequest).Value == 0 {
+			return nil, errDenied
+		}
+
+		return &testCompileResponse{Value: 42}, nil
+	}))
+	ctx := context.Background()
+
+	_, err := method(ctx, &testCompileRequest{})
+	a.Equal(err, errDenied)
+
+	resp, err := method(ctx, &testCompileRequest{Value: 1})
+	a.NilError(err)
+	a.Equal(resp.Value, 42)
+}
@@ -20,20 +20,40 @@ type funcInfo struct {
 
 // Export exports a method to RPC.
 // The name of method will be converted to kebab-case.
-func Export[Request, Response any](method HandlerFunc[Request, Response]) {
+//
+// Export panics if there is another exported method with the same name in the same package.
+func Export[Request, Response any](method HandlerFunc[Request, Response], opts ...Option) {
 	val := reflect.ValueOf(method)
 	info := parseFuncInfo(val)
-	compiled := Compile(method)
 	name := xstrings.ToKebabCase(info.Name)
-	register(info.Package, info.Name, name, reflect.ValueOf(compiled))
+	export(info.Package, info.Name, name, method, opts)
 }
 
 // ExportName exports a method to RPC with specified name.
-func ExportName[Request, Response any](name string, method HandlerFunc[Request, Response]) {
+//
+// ExportName panics if there is another exported method with the same name in the same package.
+func ExportName[Request, Response any](name string, method HandlerFunc[Request, Response], opts ...Option) {
 	val := reflect.ValueOf(method)
 	info := parseFuncInfo(val)
-	compiled := Compile(method)
-	register(info.Package, info.Name, name, reflect.ValueOf(compiled))
+	export(info.Package, info.Name, name, method, opts)
+}
+
+func export[Request, Response any](pkg, funcName, name string, method HandlerFunc[Request, Response], opts []Option) {
+	info := &HandlerInfo{
+		Package:  pkg,
+		Name:     name,
+		FuncName: funcName,
+	}
+	o := newOptions(opts)
+	compiled := compile(info, method, o)
+	register(&rpc.Handler{
+		Package:  pkg,
+		Name:     name,
+		FuncName: funcName,
+		Func:     reflect.ValueOf(compiled),
+		HTTP:     o.http,
+		Public:   o.public,
+	})
 }
 
 func parseFuncInfo(val reflect.Value) *funcInfo {
@@ -55,13 +75,7 @@ func parseFuncInfo(val reflect.Value) *funcInfo {
 	}
 }
 
-func register(pkg, funcName, name string, val reflect.Value) {
+func register(handler *rpc.Handler) {
 	registry := rpc.DefaultRegistry()
-	handler := &rpc.Handler{
-		Package:  pkg,
-		Name:     name,
-		FuncName: funcName,
-		Func:     val,
-	}
-	registry.Register(handler)
+	errors.Check(registry.Register(handler))
 }
@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"io"
+	"net/textproto"
+
+	"github.com/go-shana/core/errors"
+)
+
+var errFileNotUploaded = errors.New("rpc: file is not uploaded")
+
+// File is an uploaded file, e.g. a part in multipart/form-data request.
+//
+// Declare a field in type *File or []*File in request struct to receive uploaded files.
+// The field name follows the same rule as other fields.
+//
+//	type UploadRequest struct {
+//	    Title  string       `json:"title"`
+//	    Avatar *rpc.File    `json:"avatar"`
+//	    Photos []*rpc.File  `json:"photos"`
+//	}
+//
+// The content of a file is buffered in memory or a temporary file before handler is called,
+// and it's only available before handler returns.
+type File struct {
+	Filename string               // The filename in client.
+	Header   textproto.MIMEHeader // The MIME header of the file.
+	Size     int64                // The size of the file in bytes.
+
+	open func() (io.ReadCloser, error)
+}
+
+// NewFile creates a File whose content is read by open.
+// It's used by servers to pass uploaded files to handlers.
+func NewFile(filename string, header textproto.MIMEHeader, size int64, open func() (io.ReadCloser, error)) *File {
+	return &File{
+		Filename: filename,
+		Header:   header,
+		Size:     size,
+		open:     open,
+	}
+}
+
+// ContentType returns the Content-Type of the file.
+func (f *File) ContentType() string {
+	return f.Header.Get("Content-Type")
+}
+
+// Open opens the file to read its content.
+// The caller must close the returned reader.
+func (f *File) Open() (io.ReadCloser, error) {
+	if f.open == nil {
+		return nil, errFileNotUploaded
+	}
+
+	return f.open()
+}
@@ -0,0 +1,115 @@
+package httpjson
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+var errUnauthenticated = errors.New("httpjson: request is not authenticated")
+
+// Authenticator authenticates a request.
+//
+// Authenticate returns nil principal and nil error if r doesn't carry any credential
+// recognized by the authenticator, so that next authenticator can try.
+// It returns an error if the credential is invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (principal *rpc.Principal, err error)
+}
+
+// AuthenticatorFunc is a function implementing Authenticator.
+type AuthenticatorFunc func(r *http.Request) (principal *rpc.Principal, err error)
+
+var _ Authenticator = AuthenticatorFunc(nil)
+
+// Authenticate calls f(r).
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (principal *rpc.Principal, err error) {
+	return f(r)
+}
+
+var (
+	authMu         sync.Mutex
+	authenticators []Authenticator
+)
+
+// RegisterAuthenticator registers authenticators for all servers created after.
+// Registered authenticators run after built-in authenticators configured in AuthConfig.
+//
+// If there is any authenticator, all handlers require authentication
+// except the ones exported with rpc.Public().
+func RegisterAuthenticator(auth ...Authenticator) {
+	authMu.Lock()
+	defer authMu.Unlock()
+
+	for _, a := range auth {
+		if a != nil {
+			authenticators = append(authenticators, a)
+		}
+	}
+}
+
+// AuthConfig is the config of built-in authenticators.
+type AuthConfig struct {
+	JWT  JWTConfig  `shana:"jwt"`  // Verify JWT in "Authorization: Bearer" header.
+	HMAC HMACConfig `shana:"hmac"` // Verify HMAC-signed requests.
+}
+
+// Validate validates the config.
+func (c *AuthConfig) Validate(ctx context.Context) {
+	c.JWT.Validate(ctx)
+	c.HMAC.Validate(ctx)
+}
+
+// NewAuthenticator creates an Authenticator trying built-in authenticators configured in config
+// and authenticators registered by RegisterAuthenticator one by one.
+// It returns nil if there is no authenticator.
+//
+// It's used by other transports to authenticate requests in the same way as Router.
+func NewAuthenticator(config *AuthConfig) (auth Authenticator, err error) {
+	chain, err := newAuthChain(config)
+
+	if err != nil || len(chain) == 0 {
+		return
+	}
+
+	auth = chain
+	return
+}
+
+// authChain tries authenticators one by one.
+type authChain []Authenticator
+
+// newAuthChain creates authenticators configured in config and registered by RegisterAuthenticator.
+// It returns nil if there is no authenticator.
+func newAuthChain(config *AuthConfig) (chain authChain, err error) {
+	defer errors.Handle(&err)
+
+	if config.JWT.Enabled() {
+		chain = append(chain, errors.Check1(NewJWTAuthenticator(&config.JWT)))
+	}
+
+	if config.HMAC.Enabled() {
+		chain = append(chain, errors.Check1(NewHMACAuthenticator(&config.HMAC)))
+	}
+
+	authMu.Lock()
+	defer authMu.Unlock()
+
+	chain = append(chain, authenticators...)
+	return
+}
+
+// Authenticate returns the principal authenticated by the first authenticator recognizing r.
+func (chain authChain) Authenticate(r *http.Request) (principal *rpc.Principal, err error) {
+	for _, auth := range chain {
+		if principal, err = auth.Authenticate(r); err != nil || principal != nil {
+			return
+		}
+	}
+
+	err = errUnauthenticated
+	return
+}
@@ -0,0 +1,244 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testAuthRequest struct{}
+
+func (req *testAuthRequest) Validate(ctx context.Context) {
+	if rpc.PrincipalFrom(ctx) == nil {
+		errors.Throwf("principal is required")
+	}
+}
+
+type testAuthResponse struct {
+	Subject string `json:"subject"`
+	Scheme  string `json:"scheme"`
+}
+
+func testAuthWhoAmI(ctx context.Context, req *testAuthRequest) (resp *testAuthResponse, err error) {
+	p := rpc.PrincipalFrom(ctx)
+	resp = &testAuthResponse{
+		Subject: p.Subject,
+		Scheme:  p.Scheme,
+	}
+	return
+}
+
+func testAuthPublic(ctx context.Context, req *testClientRequest) (resp *testClientResponse, err error) {
+	resp = &testClientResponse{
+		Greeting: fmt.Sprint("public: ", rpc.PrincipalFrom(ctx) == nil),
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testAuthWhoAmI)
+	rpc.Export(testAuthPublic, rpc.Public())
+}
+
+func signTestJWT(alg, kid string, claims map[string]any, sign func(signed []byte) []byte) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func writeTestFile(t *testing.T, name, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func doAuthRequest(a *assert.A, url string, setup func(req *http.Request)) (int, *testAuthResponse, string) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte("{}")))
+	a.NilError(err)
+	req.Header.Set("Content-Type", contentTypeJSON)
+
+	if setup != nil {
+		setup(req)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	a.NilError(err)
+	defer resp.Body.Close()
+
+	data := &testAuthResponse{}
+	body := &Response{Data: data}
+	a.NilError(json.NewDecoder(resp.Body).Decode(body))
+	return resp.StatusCode, data, body.Error
+}
+
+func TestAuthJWTHS256(t *testing.T) {
+	a := assert.New(t)
+	secret := "shana-secret"
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Auth: AuthConfig{
+			JWT: JWTConfig{
+				SecretFile: writeTestFile(t, "secret", secret+"\n"),
+				Issuer:     "shana",
+			},
+		},
+	}))
+	defer server.Close()
+
+	sign := func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	token := signTestJWT(jwtHS256, "", map[string]any{"sub": "user-1", "iss": "shana", "exp": exp}, sign)
+
+	status, data, _ := doAuthRequest(a, server.URL+"/test-auth-who-am-i", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	})
+	a.Equal(status, http.StatusOK)
+	a.Equal(data, &testAuthResponse{Subject: "user-1", Scheme: schemeJWT})
+
+	status, _, msg := doAuthRequest(a, server.URL+"/test-auth-who-am-i", nil)
+	a.Equal(status, http.StatusUnauthorized)
+	a.Equal(msg, errUnauthenticated.Error())
+
+	expired := signTestJWT(jwtHS256, "", map[string]any{"sub": "user-1", "iss": "shana", "exp": time.Now().Add(-time.Hour).Unix()}, sign)
+	status, _, msg = doAuthRequest(a, server.URL+"/test-auth-who-am-i", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+expired)
+	})
+	a.Equal(status, http.StatusUnauthorized)
+	a.Equal(msg, errTokenExpired.Error())
+
+	wrongIssuer := signTestJWT(jwtHS256, "", map[string]any{"sub": "user-1", "iss": "other"}, sign)
+	status, _, _ = doAuthRequest(a, server.URL+"/test-auth-who-am-i", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+wrongIssuer)
+	})
+	a.Equal(status, http.StatusUnauthorized)
+
+	// Algorithm "none" must be rejected.
+	none := signTestJWT("none", "", map[string]any{"sub": "user-1", "iss": "shana"}, func([]byte) []byte { return nil })
+	status, _, _ = doAuthRequest(a, server.URL+"/test-auth-who-am-i", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+none)
+	})
+	a.Equal(status, http.StatusUnauthorized)
+
+	// Public handler doesn't require authentication.
+	resp, err := http.Get(server.URL + "/test-auth-public")
+	a.NilError(err)
+	defer resp.Body.Close()
+	a.Equal(resp.StatusCode, http.StatusOK)
+}
+
+func TestAuthJWTRS256(t *testing.T) {
+	a := assert.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.NilError(err)
+
+	jwks, _ := json.Marshal(map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	})
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Auth: AuthConfig{
+			JWT: JWTConfig{
+				JWKSFile: writeTestFile(t, "jwks.json", string(jwks)),
+				Audience: "api",
+			},
+		},
+	}))
+	defer server.Close()
+
+	sign := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		a.NilError(err)
+		return sig
+	}
+	token := signTestJWT(jwtRS256, "k1", map[string]any{"sub": "user-2", "aud": []string{"web", "api"}}, sign)
+
+	status, data, _ := doAuthRequest(a, server.URL+"/test-auth-who-am-i", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	})
+	a.Equal(status, http.StatusOK)
+	a.Equal(data.Subject, "user-2")
+
+	unknownKid := signTestJWT(jwtRS256, "k2", map[string]any{"sub": "user-2", "aud": "api"}, sign)
+	status, _, _ = doAuthRequest(a, server.URL+"/test-auth-who-am-i", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+unknownKid)
+	})
+	a.Equal(status, http.StatusUnauthorized)
+}
+
+func TestAuthHMAC(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Auth: AuthConfig{
+			HMAC: HMACConfig{
+				KeysFile: writeTestFile(t, "keys.yaml", "svc-a: secret-a\n"),
+			},
+		},
+	}))
+	defer server.Close()
+
+	sign := func(keyID, secret string, ts int64) func(req *http.Request) {
+		return func(req *http.Request) {
+			bodyHash := sha256.Sum256([]byte("{}"))
+			sig := SignRequest([]byte(secret), req.Method, req.URL.RequestURI(), ts, bodyHash[:])
+			req.Header.Set("Authorization", fmt.Sprintf("HMAC-SHA256 Credential=%v, Timestamp=%v, Signature=%v",
+				keyID, strconv.FormatInt(ts, 10), hex.EncodeToString(sig)))
+		}
+	}
+	now := time.Now().Unix()
+
+	status, data, _ := doAuthRequest(a, server.URL+"/test-auth-who-am-i", sign("svc-a", "secret-a", now))
+	a.Equal(status, http.StatusOK)
+	a.Equal(data, &testAuthResponse{Subject: "svc-a", Scheme: schemeHMAC})
+
+	status, _, msg := doAuthRequest(a, server.URL+"/test-auth-who-am-i", sign("svc-a", "wrong", now))
+	a.Equal(status, http.StatusUnauthorized)
+	a.Equal(msg, errInvalidSignature.Error())
+
+	status, _, msg = doAuthRequest(a, server.URL+"/test-auth-who-am-i", sign("svc-a", "secret-a", now-3600))
+	a.Equal(status, http.StatusUnauthorized)
+	a.Equal(msg, errSignatureExpired.Error())
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"Shana"}`))
+	_, err := hashBody(req, 4)
+	a.Assert(errors.Is(err, errRequestTooLarge))
+}
@@ -0,0 +1,282 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+const (
+	defaultBatchPath        = "/_shana/batch"
+	defaultBatchMaxSize     = 20
+	defaultBatchMaxBodySize = 4 << 20
+)
+
+var (
+	errBatchTooLarge   = errors.New("httpjson: too many requests in batch")
+	errInvalidCall     = errors.New("httpjson: invalid call path")
+	errUnsupportedCall = errors.New("httpjson: handler cannot be called in batch or WebSocket")
+)
+
+// BatchConfig is the config of batch endpoint.
+//
+// The batch endpoint accepts a POST request with a JSON array like:
+//
+//	[{"path": "/foo/bar", "params": {"name": "shana"}}, ...]
+//
+// Every entry is dispatched concurrently as a POST request with params as JSON body
+// through the same route, authentication and rate limiting as a normal request.
+// The response is a JSON array of Response in the same order as entries.
+type BatchConfig struct {
+	Enabled     bool   `shana:"enabled"`       // Enable batch endpoint.
+	Path        string `shana:"path"`          // The path of batch endpoint. Default is "/_shana/batch".
+	MaxSize     int    `shana:"max_size"`      // Max entries in a batch. Default is 20.
+	MaxBodySize int64  `shana:"max_body_size"` // Max size of a batch body in bytes. Default is 4MiB.
+}
+
+// Validate validates the config.
+func (c *BatchConfig) Validate(ctx context.Context) {
+	if c.Path != "" && c.Path[0] != '/' {
+		errors.Throwf("httpjson: batch path must start with '/' [path=%v]", c.Path)
+		return
+	}
+
+	if c.MaxSize < 0 {
+		errors.Throwf("httpjson: invalid batch max size [max_size=%v]", c.MaxSize)
+		return
+	}
+
+	if c.MaxBodySize < 0 {
+		errors.Throwf("httpjson: invalid batch max body size [max_body_size=%v]", c.MaxBodySize)
+		return
+	}
+}
+
+func (c *BatchConfig) path() string {
+	if c.Path == "" {
+		return defaultBatchPath
+	}
+
+	return c.Path
+}
+
+func (c *BatchConfig) maxSize() int {
+	if c.MaxSize == 0 {
+		return defaultBatchMaxSize
+	}
+
+	return c.MaxSize
+}
+
+func (c *BatchConfig) maxBodySize() int64 {
+	if c.MaxBodySize == 0 {
+		return defaultBatchMaxBodySize
+	}
+
+	return c.MaxBodySize
+}
+
+type batchEntry struct {
+	Path   string          `json:"path"`
+	Params json.RawMessage `json:"params"`
+}
+
+// batchResponseWriter buffers a response of a batch entry.
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+var _ http.ResponseWriter = new(batchResponseWriter)
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.body.Write(data)
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// serveBatch returns a http.HandlerFunc serving batch requests.
+func (r *Router) serveBatch(config *Config) http.HandlerFunc {
+	path := config.Batch.path()
+	maxSize := config.Batch.maxSize()
+	maxBodySize := config.Batch.maxBodySize()
+	compression := &config.Compression
+	compress := compression.isEnabled(path)
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST, OPTIONS")
+
+			if req.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			writeError(w, req, http.StatusMethodNotAllowed, errMethodNotAllowed)
+			return
+		}
+
+		// Buffer body so that authenticators can read it, e.g. HMAC signs body.
+		body, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, maxBodySize))
+		req.Body.Close()
+
+		if err != nil {
+			err = checkBodySize(err)
+			writeError(w, req, errorStatus(err), err)
+			return
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		ctx := req.Context()
+
+		// Authenticate batch request once so that all entries share the same principal.
+		if len(r.auth) != 0 {
+			if principal, err := r.auth.Authenticate(req); err == nil {
+				ctx = rpc.WithPrincipal(ctx, principal)
+			}
+		}
+
+		var entries []batchEntry
+
+		if err := sonic.Unmarshal(body, &entries); err != nil {
+			writeError(w, req, http.StatusBadRequest, err)
+			return
+		}
+
+		if len(entries) > maxSize {
+			writeError(w, req, http.StatusRequestEntityTooLarge, errBatchTooLarge)
+			return
+		}
+
+		results := make([][]byte, len(entries))
+		wg := &sync.WaitGroup{}
+		wg.Add(len(entries))
+
+		for i := range entries {
+			go func(i int) {
+				defer wg.Done()
+				entry := &entries[i]
+				results[i] = r.dispatch(ctx, req, strconv.Itoa(i), entry.Path, entry.Params)
+			}(i)
+		}
+
+		wg.Wait()
+
+		buf := &bytes.Buffer{}
+		buf.WriteByte('[')
+
+		for i, result := range results {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			buf.Write(result)
+		}
+
+		buf.WriteString("]\n")
+		w.Header().Set("Content-Type", contentTypeHeader(contentTypeJSON))
+		writeBody(w, req, compression, compress, http.StatusOK, buf.Bytes())
+	}
+}
+
+// dispatch serves a POST request to path with params as JSON body on behalf of parent.
+// The request ID is derived from parent's if parent has one.
+// It's used to serve batch entries and WebSocket calls.
+func (r *Router) dispatch(ctx context.Context, parent *http.Request, id, path string, params json.RawMessage) []byte {
+	rw := &batchResponseWriter{
+		header: http.Header{},
+	}
+
+	if path == "" || path[0] != '/' {
+		return encodeFailure(errInvalidCall)
+	}
+
+	if len(params) == 0 || string(params) == "null" {
+		params = json.RawMessage("{}")
+	}
+
+	ctx = context.WithValue(ctx, dispatchedKey{}, true)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(params))
+
+	// Builtin endpoints like batch itself cannot be dispatched.
+	// Router matches decoded path, so the check must use req.URL.Path instead of path.
+	if err != nil || r.builtins[req.URL.Path] != nil {
+		return encodeFailure(errInvalidCall)
+	}
+
+	req.RemoteAddr = parent.RemoteAddr
+	req.Header = parent.Header.Clone()
+
+	for _, key := range dispatchDroppedHeaders {
+		req.Header.Del(key)
+	}
+
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Accept", contentTypeJSON)
+
+	if parentID := parent.Header.Get(headerRequestID); parentID != "" {
+		req.Header.Set(headerRequestID, parentID+"-"+id)
+	}
+
+	r.ServeHTTP(rw, req)
+
+	if rw.status == http.StatusNoContent || rw.body.Len() == 0 {
+		return encodeFailure(errUnsupportedCall)
+	}
+
+	if ct := rw.header.Get("Content-Type"); ct != contentTypeHeader(contentTypeJSON) {
+		return encodeFailure(errUnsupportedCall)
+	}
+
+	return bytes.TrimSpace(rw.body.Bytes())
+}
+
+type dispatchedKey struct{}
+
+// isDispatched reports whether r is dispatched from a batch entry or a WebSocket call.
+func isDispatched(r *http.Request) bool {
+	dispatched, _ := r.Context().Value(dispatchedKey{}).(bool)
+	return dispatched
+}
+
+// Headers of parent request which don't apply to dispatched requests.
+var dispatchDroppedHeaders = []string{
+	"Content-Length",
+	"Content-Encoding",
+	"Accept-Encoding",
+	"Connection",
+	"Upgrade",
+	"Sec-Websocket-Key",
+	"Sec-Websocket-Version",
+	"Sec-Websocket-Extensions",
+	"Sec-Websocket-Protocol",
+}
+
+// encodeFailure returns an encoded Response with err.
+func encodeFailure(err error) []byte {
+	data, _ := sonic.Marshal(&Response{
+		Error: err.Error(),
+	})
+	return data
+}
@@ -0,0 +1,138 @@
+package httpjson
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+var testBatchStreamCalls atomic.Int32
+
+func testBatchStream(ctx context.Context, req *testStreamRequest, stream rpc.Stream[testStreamItem]) error {
+	testBatchStreamCalls.Add(1)
+	return nil
+}
+
+func init() {
+	rpc.ExportStream(testBatchStream)
+}
+
+func TestBatch(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Batch: BatchConfig{
+			Enabled: true,
+			MaxSize: 4,
+		},
+	}))
+	defer server.Close()
+
+	post := func(body string) (*http.Response, []map[string]any) {
+		resp, err := http.Post(server.URL+defaultBatchPath, contentTypeJSON, strings.NewReader(body))
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		var results []map[string]any
+
+		if resp.StatusCode == http.StatusOK {
+			a.NilError(json.NewDecoder(resp.Body).Decode(&results))
+		}
+
+		return resp, results
+	}
+
+	resp, results := post(`[
+		{"path": "/test-client-greet", "params": {"name": "Shana"}},
+		{"path": "/users/42"},
+		{"path": "/test-client-greet", "params": {}},
+		{"path": "/not-found"}
+	]`)
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Equal(len(results), 4)
+	a.Equal(results[0]["data"], map[string]any{"greeting": "Hello, Shana"})
+	a.Equal(results[1]["error"], errMethodNotAllowed.Error())
+	a.Equal(results[2]["code"], float64(1001))
+	a.Equal(results[3]["error"], errNotFound.Error())
+
+	resp, results = post(`[{"path": "/_shana/batch", "params": []}, {"path": "/test-stream-count"}]`)
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Equal(results[0]["error"], errInvalidCall.Error())
+	a.Equal(results[1]["error"], errUnsupportedCall.Error())
+
+	// Escaped path of builtin endpoints cannot be dispatched.
+	// Streaming handlers must be rejected before they are called.
+	resp, results = post(`[{"path": "/_shana/%62atch", "params": []}, {"path": "/test-batch-stream"}]`)
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Equal(results[0]["error"], errInvalidCall.Error())
+	a.Equal(results[1]["error"], errUnsupportedCall.Error())
+	a.Equal(testBatchStreamCalls.Load(), int32(0))
+
+	resp, _ = post(`[{"path": "/a"}, {"path": "/b"}, {"path": "/c"}, {"path": "/d"}, {"path": "/e"}]`)
+	a.Equal(resp.StatusCode, http.StatusRequestEntityTooLarge)
+
+	resp, _ = post(`{}`)
+	a.Equal(resp.StatusCode, http.StatusBadRequest)
+}
+
+func TestBatchHMAC(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Auth: AuthConfig{
+			HMAC: HMACConfig{
+				KeysFile: writeTestFile(t, "keys.yaml", "svc-a: secret-a\n"),
+			},
+		},
+		Batch: BatchConfig{
+			Enabled:     true,
+			MaxBodySize: 1024,
+		},
+	}))
+	defer server.Close()
+
+	post := func(body string) (*http.Response, []map[string]any) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+defaultBatchPath, strings.NewReader(body))
+		a.NilError(err)
+
+		// Batch request is signed as a whole.
+		ts := time.Now().Unix()
+		bodyHash := sha256.Sum256([]byte(body))
+		sig := SignRequest([]byte("secret-a"), req.Method, req.URL.RequestURI(), ts, bodyHash[:])
+		req.Header.Set("Content-Type", contentTypeJSON)
+		req.Header.Set("Authorization", fmt.Sprintf("HMAC-SHA256 Credential=svc-a, Timestamp=%v, Signature=%v", ts, hex.EncodeToString(sig)))
+
+		resp, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		var results []map[string]any
+
+		if resp.StatusCode == http.StatusOK {
+			a.NilError(json.NewDecoder(resp.Body).Decode(&results))
+		}
+
+		return resp, results
+	}
+
+	resp, results := post(`[{"path": "/test-auth-who-am-i"}, {"path": "/test-auth-who-am-i", "params": {}}]`)
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Equal(results, []map[string]any{
+		{"data": map[string]any{"subject": "svc-a", "scheme": schemeHMAC}},
+		{"data": map[string]any{"subject": "svc-a", "scheme": schemeHMAC}},
+	})
+
+	resp, _ = post(`[{"path": "/test-auth-who-am-i", "params": {"padding": "` + strings.Repeat("x", 1024) + `"}}]`)
+	a.Equal(resp.StatusCode, http.StatusRequestEntityTooLarge)
+}
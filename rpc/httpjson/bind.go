@@ -0,0 +1,160 @@
+package httpjson
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-shana/core/data"
+)
+
+// Sources of bound fields. The source is also the struct tag name.
+const (
+	bindHeader = "header"
+	bindCookie = "cookie"
+	bindPath   = "path"
+)
+
+var bindSources = []string{bindHeader, bindCookie, bindPath}
+
+// boundField is a request field bound to a header, a cookie or a path parameter.
+type boundField struct {
+	index  int
+	source string
+	name   string
+	typ    reflect.Type
+}
+
+var boundFieldsCache sync.Map // map[reflect.Type][]*boundField
+
+// boundFields returns all fields in struct t with tags like `header:"X-Tenant-Id"`,
+// `cookie:"session"` or `path:"id"`.
+func boundFields(t reflect.Type) []*boundField {
+	if cached, ok := boundFieldsCache.Load(t); ok {
+		return cached.([]*boundField)
+	}
+
+	var fields []*boundField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, source := range bindSources {
+			name := strings.TrimSpace(field.Tag.Get(source))
+
+			if name == "" || name == "-" {
+				continue
+			}
+
+			if source == bindHeader {
+				name = http.CanonicalHeaderKey(name)
+			}
+
+			fields = append(fields, &boundField{
+				index:  i,
+				source: source,
+				name:   name,
+				typ:    field.Type,
+			})
+			break
+		}
+	}
+
+	cached, _ := boundFieldsCache.LoadOrStore(t, fields)
+	return cached.([]*boundField)
+}
+
+// findBoundField returns the field in t bound to source with name.
+func findBoundField(t reflect.Type, source, name string) *boundField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, field := range boundFields(t) {
+		if field.source == source && field.name == name {
+			return field
+		}
+	}
+
+	return nil
+}
+
+// isBoundField reports whether field is bound to a header, a cookie or a path parameter.
+func isBoundField(field reflect.StructField) bool {
+	for _, source := range bindSources {
+		if name := field.Tag.Get(source); name != "" && name != "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bindFields sets bound fields in the struct pointed by ptr with headers, cookies and path parameters in r.
+//
+// Values are converted in the same way as query string.
+// A bound field is always set by its source, so that values in query string or body cannot override it.
+// If the source is absent, the field is set to zero value.
+func bindFields(ptr reflect.Value, r *http.Request) error {
+	val := ptr.Elem()
+	fields := boundFields(val.Type())
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	params := pathParamsFrom(r.Context())
+	dec := &data.Decoder{}
+
+	for _, field := range fields {
+		var values []string
+
+		switch field.source {
+		case bindHeader:
+			values = r.Header.Values(field.name)
+
+		case bindCookie:
+			for _, cookie := range r.Cookies() {
+				if cookie.Name == field.name {
+					values = append(values, cookie.Value)
+				}
+			}
+
+		case bindPath:
+			if v, ok := params[field.name]; ok {
+				values = []string{v}
+			}
+		}
+
+		fv := val.Field(field.index)
+
+		if len(values) == 0 {
+			fv.Set(reflect.Zero(field.typ))
+			continue
+		}
+
+		v, err := convertValues(field.typ, values)
+
+		if err == nil {
+			d := data.Make(data.RawData{"v": v})
+			fv.Set(reflect.Zero(field.typ))
+			err = dec.DecodeField(d, []string{"v"}, fv.Addr().Interface())
+		}
+
+		if err != nil {
+			return fmt.Errorf("httpjson: invalid value of %v %v [value=%v] [err=%w]", field.source, field.name, strings.Join(values, ","), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testBindRequest struct {
+	ID        int64    `json:"-" path:"id"`
+	TenantID  string   `json:"tenantId" header:"x-tenant-id"`
+	Languages []string `json:"-" header:"Accept-Language"`
+	Retry     *int     `json:"-" header:"X-Retry"`
+	Session   string   `json:"-" cookie:"session"`
+	Name      string   `json:"name"`
+}
+
+var errTestBindNoTenant = errors.NewErrorCode(1200, "tenant is required")
+
+func (req *testBindRequest) Validate(ctx context.Context) {
+	if req.TenantID == "" {
+		errors.Throw(errTestBindNoTenant)
+	}
+}
+
+type testBindResponse struct {
+	ID        int64    `json:"id"`
+	TenantID  string   `json:"tenantId"`
+	Languages []string `json:"languages"`
+	Retry     *int     `json:"retry"`
+	Session   string   `json:"session"`
+	Name      string   `json:"name"`
+}
+
+func testBindGet(ctx context.Context, req *testBindRequest) (resp *testBindResponse, err error) {
+	resp = &testBindResponse{
+		ID:        req.ID,
+		TenantID:  req.TenantID,
+		Languages: req.Languages,
+		Retry:     req.Retry,
+		Session:   req.Session,
+		Name:      req.Name,
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testBindGet, rpc.HTTP("POST", "/bind/{id}"))
+}
+
+func TestBindFields(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	do := func(path, body string, header http.Header) (int, map[string]any) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+		a.NilError(err)
+
+		for k, vs := range header {
+			req.Header[k] = vs
+		}
+
+		req.Header.Set("Content-Type", contentTypeJSON)
+		resp, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		var result map[string]any
+		a.NilError(json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	status, result := do("/bind/42", `{"name": "Shana", "tenantId": "evil"}`, http.Header{
+		"X-Tenant-Id":     {"t1"},
+		"Accept-Language": {"zh-CN", "en"},
+		"X-Retry":         {"3"},
+		"Cookie":          {"session=abc; other=x"},
+	})
+	a.Equal(status, http.StatusOK)
+	a.Equal(result["data"], map[string]any{
+		"id":        float64(42),
+		"tenantId":  "t1",
+		"languages": []any{"zh-CN", "en"},
+		"retry":     float64(3),
+		"session":   "abc",
+		"name":      "Shana",
+	})
+
+	// Bound fields cannot be set by body or query string.
+	status, result = do("/bind/42?tenantId=evil", `{"tenantId": "evil"}`, nil)
+	a.Equal(status, http.StatusBadRequest)
+	a.Equal(result["code"], float64(1200))
+
+	status, result = do("/bind/42", `{}`, http.Header{
+		"X-Tenant-Id": {"t1"},
+		"X-Retry":     {"many"},
+	})
+	a.Equal(status, http.StatusBadRequest)
+	a.Equal(result["error"], "httpjson: invalid value of header X-Retry [value=many] [err=invalid syntax]")
+}
+
+func TestOpenAPIBoundFields(t *testing.T) {
+	a := assert.New(t)
+	doc := &openAPIDocument{
+		types:   map[reflect.Type]string{},
+		names:   map[string]reflect.Type{},
+		schemas: map[string]*openAPISchema{},
+	}
+	reqType := reflect.TypeOf(testBindRequest{})
+
+	a.Equal(doc.boundParameters(reqType), []*openAPIParameter{
+		{Name: "X-Tenant-Id", In: "header", Schema: &openAPISchema{Type: "string"}},
+		{Name: "Accept-Language", In: "header", Schema: &openAPISchema{Type: "array", Items: &openAPISchema{Type: "string"}}},
+		{Name: "X-Retry", In: "header", Schema: &openAPISchema{Type: "integer", Format: "int64"}},
+		{Name: "session", In: "cookie", Schema: &openAPISchema{Type: "string"}},
+	})
+	a.Equal(doc.pathParameters("/bind/{id}", reqType), []*openAPIParameter{
+		{Name: "id", In: "path", Required: true, Schema: &openAPISchema{Type: "integer", Format: "int64"}},
+	})
+	a.Equal(doc.properties(reqType), map[string]*openAPISchema{
+		"name": {Type: "string"},
+	})
+}
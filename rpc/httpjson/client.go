@@ -0,0 +1,365 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/errors"
+)
+
+// Client is a HTTP JSON client calling Shana HTTP JSON services.
+type Client struct {
+	BaseURL    string       // The base URL of the service, e.g. "http://127.0.0.1:9696".
+	HTTPClient *http.Client // The HTTP client to send requests. If it's nil, http.DefaultClient is used.
+	Header     http.Header  // Extra headers sent with every request.
+}
+
+// NewClient creates a new HTTP JSON client with the base URL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL: baseURL,
+	}
+}
+
+// clientResponse is the Response with typed data.
+type clientResponse[T any] struct {
+	Code    any    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Data    *T     `json:"data,omitempty"`
+}
+
+// Call sends req to the handler at path, e.g. "/pkg/method-name", and decodes the response data.
+// The req is sent as JSON body in a POST request.
+// Use CallRoute to call a handler exported with a customized route like rpc.HTTP("PUT", "/users/{id}").
+//
+// If the service responds with a code and a message,
+// the returned error is an errors.ErrorCode whose type parameter depends on the JSON type of the code.
+// An integer code is returned as errors.ErrorCode[int], a string code is returned as errors.ErrorCode[string]
+// and any other code is returned as errors.ErrorCode[any].
+// If the service responds with an error without code, the returned error is a plain errors.Error.
+func Call[Request, Response any](ctx context.Context, client *Client, path string, req *Request) (resp *Response, err error) {
+	defer errors.Handle(&err)
+
+	body := errors.Check1(sonic.Marshal(req))
+	httpReq := errors.Check1(client.newRequest(ctx, http.MethodPost, path, bytes.NewReader(body)))
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp = errors.Check1(do[Response](client, httpReq, path))
+	return
+}
+
+// CallRoute sends req to the handler exported with route, e.g. "PUT /users/{id}", and decodes the response data.
+// It's the same as Call except the method and path.
+//
+// Parameters in the path template are set by fields with the `path` tag, or fields with the same JSON names.
+// Fields with `header` or `cookie` tags are sent as headers or cookies if they are not zero.
+// In GET and HEAD requests, other fields are sent as query string, e.g. "?filter.status=open&ids=1&ids=2".
+// Values in query string are formatted as in JSON, so []byte and time.Duration fields cannot be sent in this way.
+// Otherwise, req is sent as JSON body.
+func CallRoute[Request, Response any](ctx context.Context, client *Client, route string, req *Request) (resp *Response, err error) {
+	defer errors.Handle(&err)
+
+	method, pattern, ok := strings.Cut(strings.TrimSpace(route), " ")
+
+	if !ok {
+		errors.Throwf("httpjson: route must be in the form of \"METHOD /path\" [route=%v]", route)
+	}
+
+	method = strings.ToUpper(method)
+	pattern = strings.TrimSpace(pattern)
+	tr := errors.Check1(parseTemplate(method, pattern, nil))
+
+	body := errors.Check1(sonic.Marshal(req))
+	var fields map[string]any
+	var val reflect.Value
+
+	if req != nil {
+		dec := sonic.ConfigDefault.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		errors.Check(dec.Decode(&fields))
+		val = reflect.ValueOf(req).Elem()
+	}
+
+	segments := make([]string, len(tr.segments))
+
+	for i, name := range tr.params {
+		if name == "" {
+			segments[i] = tr.segments[i]
+			continue
+		}
+
+		v, ok := routeParam(val, fields, name)
+
+		if !ok {
+			errors.Throwf("httpjson: missing path parameter [route=%v] [param=%v]", route, name)
+		}
+
+		segments[i] = url.PathEscape(v)
+		delete(fields, name)
+	}
+
+	path := "/" + strings.Join(segments, "/")
+	var bodyReader io.Reader
+
+	if method == http.MethodGet || method == http.MethodHead {
+		query := url.Values{}
+		addQueryValues(query, "", fields)
+
+		if len(query) != 0 {
+			path += "?" + query.Encode()
+		}
+	} else {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	httpReq := errors.Check1(client.newRequest(ctx, method, path, bodyReader))
+
+	if bodyReader != nil {
+		httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	if val.Kind() == reflect.Struct {
+		setBoundFields(httpReq, val)
+	}
+
+	resp = errors.Check1(do[Response](client, httpReq, path))
+	return
+}
+
+func (client *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	url := strings.TrimSuffix(client.BaseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for k, vs := range client.Header {
+		for _, v := range vs {
+			httpReq.Header.Add(k, v)
+		}
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	return httpReq, nil
+}
+
+// do sends httpReq and decodes the response data.
+func do[Response any](client *Client, httpReq *http.Request, path string) (resp *Response, err error) {
+	defer errors.Handle(&err)
+
+	httpClient := client.HTTPClient
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	httpResp := errors.Check1(httpClient.Do(httpReq))
+	defer httpResp.Body.Close()
+
+	content := errors.Check1(io.ReadAll(httpResp.Body))
+	result := &clientResponse[Response]{}
+	dec := sonic.ConfigDefault.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+
+	if err := dec.Decode(result); err != nil {
+		if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+			errors.Throwf("httpjson: unexpected HTTP status [status=%v] [path=%v]", httpResp.StatusCode, path)
+		}
+
+		errors.Throwf("httpjson: fail to decode response [path=%v] [err=%v]", path, err)
+	}
+
+	if result.Code != nil {
+		err = makeErrorCode(result.Code, result.Message)
+		return
+	}
+
+	if result.Error != "" {
+		err = errors.New(result.Error)
+		return
+	}
+
+	resp = result.Data
+
+	if resp == nil {
+		resp = new(Response)
+	}
+
+	return
+}
+
+func makeErrorCode(code any, msg string) error {
+	switch c := code.(type) {
+	case json.Number:
+		if n, err := strconv.ParseInt(string(c), 10, 0); err == nil {
+			return errors.NewErrorCode(int(n), msg)
+		}
+
+		return errors.NewErrorCode[any](c, msg)
+
+	case string:
+		return errors.NewErrorCode(c, msg)
+	}
+
+	return errors.NewErrorCode(code, msg)
+}
+
+// routeParam returns the value of path parameter name in the struct val.
+// The field with `path:"name"` takes precedence over the JSON field name in fields.
+func routeParam(val reflect.Value, fields map[string]any, name string) (string, bool) {
+	if val.IsValid() {
+		if field := findBoundField(val.Type(), bindPath, name); field != nil {
+			return formatBoundValue(val.Field(field.index))
+		}
+	}
+
+	switch v := fields[name].(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return string(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+
+	return "", false
+}
+
+// setBoundFields sets headers and cookies of r with non-zero bound fields in the struct val.
+func setBoundFields(r *http.Request, val reflect.Value) {
+	for _, field := range boundFields(val.Type()) {
+		fv := val.Field(field.index)
+
+		if fv.IsZero() {
+			continue
+		}
+
+		switch field.source {
+		case bindHeader:
+			if fv.Kind() == reflect.Slice && fv.Type() != typeOfBytes {
+				r.Header.Del(field.name)
+
+				for i := 0; i < fv.Len(); i++ {
+					if v, ok := formatBoundValue(fv.Index(i)); ok {
+						r.Header.Add(field.name, v)
+					}
+				}
+
+				continue
+			}
+
+			if v, ok := formatBoundValue(fv); ok {
+				r.Header.Set(field.name, v)
+			}
+
+		case bindCookie:
+			if v, ok := formatBoundValue(fv); ok {
+				r.AddCookie(&http.Cookie{Name: field.name, Value: v})
+			}
+		}
+	}
+}
+
+// formatBoundValue formats v in the way that a bound field can be converted from.
+func formatBoundValue(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case typeOfTime:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), true
+	case typeOfBytes:
+		return string(v.Bytes()), true
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), true
+	}
+
+	return "", false
+}
+
+// addQueryValues adds v decoded from JSON to query with key prefix.
+// Keys are joined in the syntax of unmarshalValues, e.g. "filter.status" and "items.0.name".
+func addQueryValues(query url.Values, prefix string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			key := escapeValuesKey(k)
+
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+
+			addQueryValues(query, key, v[k])
+		}
+
+	case []any:
+		for i, elem := range v {
+			switch elem.(type) {
+			case map[string]any, []any:
+				addQueryValues(query, prefix+"."+strconv.Itoa(i), elem)
+			default:
+				// Scalars are repeated keys of a slice.
+				addQueryValues(query, prefix, elem)
+			}
+		}
+
+	case string:
+		query.Add(prefix, v)
+	case json.Number:
+		query.Add(prefix, string(v))
+	case bool:
+		query.Add(prefix, strconv.FormatBool(v))
+	}
+}
+
+// escapeValuesKey escapes characters in key which have special meaning in unmarshalValues.
+func escapeValuesKey(key string) string {
+	if !strings.ContainsAny(key, `\.[]`) {
+		return key
+	}
+
+	buf := &strings.Builder{}
+
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; c {
+		case '\\', '.', '[', ']':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return buf.String()
+}
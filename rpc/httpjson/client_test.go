@@ -0,0 +1,151 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+const testPkgPrefix = "github.com/go-shana/core/rpc/httpjson"
+
+type testClientRequest struct {
+	Name string `json:"name"`
+}
+
+type testClientResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+var errTestClientNoName = errors.NewErrorCode(1001, "name is required")
+
+func testClientGreet(ctx context.Context, req *testClientRequest) (resp *testClientResponse, err error) {
+	if req.Name == "" {
+		err = errTestClientNoName
+		return
+	}
+
+	resp = &testClientResponse{
+		Greeting: "Hello, " + req.Name,
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testClientGreet)
+}
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+	}))
+}
+
+func TestClientCall(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ctx := context.Background()
+
+	resp, err := Call[testClientRequest, testClientResponse](ctx, client, "/test-client-greet", &testClientRequest{
+		Name: "Shana",
+	})
+	a.NilError(err)
+	a.Equal(resp.Greeting, "Hello, Shana")
+
+	_, err = Call[testClientRequest, testClientResponse](ctx, client, "/test-client-greet", &testClientRequest{})
+	var code errors.ErrorCode[int]
+	a.Assert(errors.As(err, &code))
+	a.Equal(code.Code(), 1001)
+	a.Equal(code.Error(), "name is required")
+}
+
+func TestClientCallRoute(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ctx := context.Background()
+
+	resp, err := CallRoute[testTemplateUserRequest, testTemplateUserResponse](ctx, client, "GET /users/{id}", &testTemplateUserRequest{
+		ID: 42,
+	})
+	a.NilError(err)
+	a.Equal(resp, &testTemplateUserResponse{ID: 42, Name: "user"})
+
+	resp, err = CallRoute[testTemplateUserRequest, testTemplateUserResponse](ctx, client, "PUT /users/{id}", &testTemplateUserRequest{
+		ID:   7,
+		Name: "Shana",
+	})
+	a.NilError(err)
+	a.Equal(resp, &testTemplateUserResponse{ID: 7, Name: "Shana"})
+
+	retry := 3
+	bindResp, err := CallRoute[testBindRequest, testBindResponse](ctx, client, "POST /bind/{id}", &testBindRequest{
+		ID:        42,
+		TenantID:  "t1",
+		Languages: []string{"zh-CN", "en"},
+		Retry:     &retry,
+		Session:   "abc",
+		Name:      "Shana",
+	})
+	a.NilError(err)
+	a.Equal(bindResp, &testBindResponse{
+		ID:        42,
+		TenantID:  "t1",
+		Languages: []string{"zh-CN", "en"},
+		Retry:     &retry,
+		Session:   "abc",
+		Name:      "Shana",
+	})
+
+	_, err = CallRoute[testTemplateUserRequest, testTemplateUserResponse](ctx, client, "/users/{id}", &testTemplateUserRequest{})
+	a.NonNilError(err)
+	_, err = CallRoute[testTemplateUserRequest, testTemplateUserResponse](ctx, client, "GET /users/{uid}", &testTemplateUserRequest{})
+	a.NonNilError(err)
+}
+
+func TestClientQueryValues(t *testing.T) {
+	a := assert.New(t)
+	closed := true
+	req := &testValuesRequest{
+		Name:    "Shana",
+		Code:    "007",
+		Count:   3,
+		Verbose: true,
+		IDs:     []int64{1, 2},
+		Filter: testValuesFilter{
+			Status: "open",
+			Closed: &closed,
+		},
+		Items:    []*testValuesItem{{Name: "book", Price: 9.5}},
+		Labels:   map[string]int{"a.b": 1},
+		Since:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Untagged: 8,
+	}
+	body, err := sonic.Marshal(req)
+	a.NilError(err)
+
+	var fields map[string]any
+	dec := sonic.ConfigDefault.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	a.NilError(dec.Decode(&fields))
+
+	query := url.Values{}
+	addQueryValues(query, "", fields)
+
+	decoded := &testValuesRequest{}
+	a.NilError(unmarshalValues(reflect.ValueOf(decoded), query))
+	a.Equal(decoded, req)
+}
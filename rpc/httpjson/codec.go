@@ -0,0 +1,182 @@
+package httpjson
+
+import (
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-shana/core/internal/global"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeMsgPack = "application/msgpack"
+	contentTypeCBOR    = "application/cbor"
+	contentTypeForm    = "application/x-www-form-urlencoded"
+)
+
+// Codec encodes responses and decodes requests in a content type.
+//
+// All codecs must respect the `json` struct field tags
+// so that request and response structs work in the same way as JSON.
+type Codec interface {
+	// Encode encodes v and writes it to w.
+	Encode(w io.Writer, v any) error
+
+	// Decode reads from r and decodes it into v.
+	// The v is always a pointer to a struct.
+	Decode(r io.Reader, v any) error
+}
+
+var (
+	codecMu sync.RWMutex
+	codecs  = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(contentTypeJSON, jsonCodec{})
+	RegisterCodec(contentTypeMsgPack, msgpackCodec{})
+	RegisterCodec("application/x-msgpack", msgpackCodec{})
+	RegisterCodec(contentTypeCBOR, cborCodec{})
+}
+
+// RegisterCodec registers codec for the contentType, e.g. "application/msgpack".
+// The contentType is a media type without any parameter.
+// If there is a codec registered for the contentType, it's replaced by codec.
+//
+// Requests are decoded by the codec matching the Content-Type header and
+// responses are encoded by the codec matching the Accept header.
+// If there is no codec matching the Accept header, JSON is used.
+func RegisterCodec(contentType string, codec Codec) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+
+	codecMu.Lock()
+	defer codecMu.Unlock()
+
+	codecs[contentType] = codec
+}
+
+func lookupCodec(contentType string) Codec {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	return codecs[contentType]
+}
+
+// requestCodec returns the codec to decode a request body with the Content-Type header.
+// If the header is empty, invalid or not registered, JSON codec is used.
+func requestCodec(header string) (contentType string, codec Codec) {
+	if mt, _, err := mime.ParseMediaType(header); err == nil {
+		if codec = lookupCodec(mt); codec != nil {
+			return mt, codec
+		}
+	}
+
+	return contentTypeJSON, jsonCodec{}
+}
+
+// responseCodec returns the codec to encode a response negotiated with the Accept header.
+// If no codec is acceptable, JSON codec is used.
+func responseCodec(accept string) (contentType string, codec Codec) {
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, acceptRange{
+			mediaType: mt,
+			q:         q,
+		})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, r := range ranges {
+		if codec = lookupCodec(r.mediaType); codec != nil {
+			contentType = r.mediaType
+			return
+		}
+	}
+
+	return contentTypeJSON, jsonCodec{}
+}
+
+// contentTypeHeader returns the Content-Type header value of contentType.
+func contentTypeHeader(contentType string) string {
+	if contentType == contentTypeJSON {
+		return "application/json; charset=utf-8"
+	}
+
+	return contentType
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	enc := sonic.ConfigDefault.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	if global.Debug() {
+		enc.SetIndent("", "  ")
+	}
+
+	return enc.Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	dec := sonic.ConfigDefault.NewDecoder(r)
+	return dec.Decode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+type cborCodec struct{}
+
+func (cborCodec) Encode(w io.Writer, v any) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+func (cborCodec) Decode(r io.Reader, v any) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
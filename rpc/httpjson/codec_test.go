@@ -0,0 +1,99 @@
+package httpjson
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	for _, contentType := range []string{contentTypeMsgPack, contentTypeCBOR, contentTypeJSON} {
+		codec := lookupCodec(contentType)
+		buf := &bytes.Buffer{}
+		a.NilError(codec.Encode(buf, &testClientRequest{Name: "Shana"}))
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/test-client-greet", buf)
+		a.NilError(err)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", contentType)
+
+		resp, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		a.Equal(resp.Header.Get("Content-Type"), contentTypeHeader(contentType))
+
+		var body struct {
+			Data testClientResponse `json:"data"`
+		}
+		a.NilError(codec.Decode(resp.Body, &body))
+		a.Equal(body.Data.Greeting, "Hello, Shana")
+	}
+}
+
+func TestCodecForm(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	resp, err := http.PostForm(server.URL+"/test-client-greet", url.Values{"name": {"Shana"}})
+	a.NilError(err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	a.NilError(err)
+	a.Assert(strings.Contains(string(body), "Hello, Shana"))
+}
+
+func TestCodecFallback(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	// Body with unknown or invalid Content-Type is decoded as JSON.
+	for _, contentType := range []string{"text/plain", "application/xml", "invalid/", ""} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/test-client-greet", strings.NewReader(`{"name": "Shana"}`))
+		a.NilError(err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		a.Use(&contentType)
+		a.NilError(err)
+		a.Equal(resp.StatusCode, http.StatusOK)
+		a.Assert(strings.Contains(string(body), "Hello, Shana"))
+	}
+}
+
+func TestResponseCodec(t *testing.T) {
+	a := assert.New(t)
+
+	cases := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", contentTypeJSON},
+		{"*/*", contentTypeJSON},
+		{"application/msgpack", contentTypeMsgPack},
+		{"application/cbor;q=0.5, application/msgpack;q=0.8", contentTypeMsgPack},
+		{"application/msgpack;q=0, application/cbor", contentTypeCBOR},
+		{"text/html", contentTypeJSON},
+	}
+
+	for _, c := range cases {
+		ct, codec := responseCodec(c.accept)
+		a.Equal(ct, c.contentType)
+		a.Assert(codec != nil)
+	}
+}
@@ -0,0 +1,262 @@
+package httpjson
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-shana/core/errors"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingGzip     = "gzip"
+	encodingZstd     = "zstd"
+	encodingIdentity = "identity"
+
+	defaultCompressionMinSize           = 1024
+	defaultCompressionMaxDecompressSize = 32 << 20
+)
+
+// CompressionConfig is the config of response compression.
+//
+// Responses are compressed with gzip or zstd negotiated by the Accept-Encoding header.
+// Request bodies compressed with gzip or zstd are always accepted according to the Content-Encoding header.
+// Streaming responses are never compressed.
+type CompressionConfig struct {
+	Disabled      bool     `shana:"disabled"`       // Disable response compression.
+	MinSize       int      `shana:"min_size"`       // Responses smaller than MinSize bytes are not compressed. Default is 1024.
+	ExcludedPaths []string `shana:"excluded_paths"` // Routes not to be compressed, e.g. "/foo/bar" or "/users/{id}".
+
+	MaxDecompressSize int64 `shana:"max_decompress_size"` // Max size of a decompressed request body in bytes. Default is 32MiB.
+}
+
+// Validate validates the config.
+func (c *CompressionConfig) Validate(ctx context.Context) {
+	if c.MinSize < 0 {
+		errors.Throwf("httpjson: invalid compression min size [min_size=%v]", c.MinSize)
+		return
+	}
+
+	for _, path := range c.ExcludedPaths {
+		if path == "" || path[0] != '/' {
+			errors.Throwf("httpjson: excluded path must start with '/' [path=%v]", path)
+			return
+		}
+	}
+
+	if c.MaxDecompressSize < 0 {
+		errors.Throwf("httpjson: invalid max decompress size [max_decompress_size=%v]", c.MaxDecompressSize)
+		return
+	}
+}
+
+// isEnabled reports whether responses of the route at path can be compressed.
+func (c *CompressionConfig) isEnabled(path string) bool {
+	if c.Disabled {
+		return false
+	}
+
+	for _, p := range c.ExcludedPaths {
+		if p == path {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *CompressionConfig) minSize() int {
+	if c.MinSize == 0 {
+		return defaultCompressionMinSize
+	}
+
+	return c.MinSize
+}
+
+func (c *CompressionConfig) maxDecompressSize() int64 {
+	if c.MaxDecompressSize == 0 {
+		return defaultCompressionMaxDecompressSize
+	}
+
+	return c.MaxDecompressSize
+}
+
+var (
+	gzipWriterPool = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(io.Discard)
+		},
+	}
+
+	// The zstd encoder is safe for concurrent EncodeAll calls.
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+)
+
+// responseEncoding returns the best content encoding negotiated with the Accept-Encoding header.
+// It returns empty string if response should not be compressed.
+func responseEncoding(acceptEncoding string) string {
+	var encoding string
+	var best float64
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		if q <= 0 || q < best {
+			continue
+		}
+
+		switch name {
+		case encodingZstd:
+		case encodingGzip:
+			// Prefer zstd to gzip if their q-values are the same.
+			if q == best && encoding == encodingZstd {
+				continue
+			}
+		default:
+			continue
+		}
+
+		encoding = name
+		best = q
+	}
+
+	return encoding
+}
+
+// writeBody writes body to w with status.
+// The body is compressed if it's allowed by config and client accepts it.
+func writeBody(w http.ResponseWriter, r *http.Request, config *CompressionConfig, compress bool, status int, body []byte) {
+	header := w.Header()
+
+	if compress {
+		header.Add("Vary", "Accept-Encoding")
+
+		if len(body) >= config.minSize() {
+			switch responseEncoding(r.Header.Get("Accept-Encoding")) {
+			case encodingGzip:
+				header.Set("Content-Encoding", encodingGzip)
+				header.Del("Content-Length")
+				w.WriteHeader(status)
+
+				gw := gzipWriterPool.Get().(*gzip.Writer)
+				defer gzipWriterPool.Put(gw)
+
+				gw.Reset(w)
+				gw.Write(body)
+				gw.Close()
+				return
+
+			case encodingZstd:
+				compressed := zstdEncoder.EncodeAll(body, nil)
+				header.Set("Content-Encoding", encodingZstd)
+				header.Set("Content-Length", strconv.Itoa(len(compressed)))
+				w.WriteHeader(status)
+				w.Write(compressed)
+				return
+			}
+		}
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// decompressBody replaces r.Body with a reader decompressing body according to the Content-Encoding header.
+// The decompressed body cannot be larger than maxSize.
+func decompressBody(r *http.Request, maxSize int64) (err error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+	switch encoding {
+	case "", encodingIdentity:
+		return
+
+	case encodingGzip:
+		var gr *gzip.Reader
+
+		if gr, err = gzip.NewReader(r.Body); err != nil {
+			return
+		}
+
+		r.Body = &decompressReader{Reader: gr, body: r.Body}
+
+	case encodingZstd:
+		var zr *zstd.Decoder
+
+		// Limit memory and window before decoding so that a crafted frame cannot allocate more than maxSize.
+		window := uint64(maxSize)
+
+		if window < zstd.MinWindowSize {
+			window = zstd.MinWindowSize
+		} else if window > zstd.MaxWindowSize {
+			window = zstd.MaxWindowSize
+		}
+
+		if zr, err = zstd.NewReader(r.Body,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(uint64(maxSize)),
+			zstd.WithDecoderMaxWindow(window),
+		); err != nil {
+			return
+		}
+
+		r.Body = &decompressReader{Reader: &zstdReader{Decoder: zr, limit: maxSize}, body: r.Body, close: zr.Close}
+
+	default:
+		return fmt.Errorf("httpjson: unsupported Content-Encoding [content-encoding=%v]", encoding)
+	}
+
+	r.Body = http.MaxBytesReader(nil, r.Body, maxSize)
+	r.Header.Del("Content-Encoding")
+	r.ContentLength = -1
+	return
+}
+
+type decompressReader struct {
+	io.Reader
+	body  io.ReadCloser
+	close func()
+}
+
+func (dr *decompressReader) Close() error {
+	if dr.close != nil {
+		dr.close()
+	}
+
+	return dr.body.Close()
+}
+
+// zstdReader reports errors of decoder limits as *http.MaxBytesError.
+type zstdReader struct {
+	*zstd.Decoder
+	limit int64
+}
+
+func (zr *zstdReader) Read(p []byte) (n int, err error) {
+	n, err = zr.Decoder.Read(p)
+
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		err = &http.MaxBytesError{Limit: zr.limit}
+	}
+
+	return
+}
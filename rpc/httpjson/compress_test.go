@@ -0,0 +1,183 @@
+package httpjson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/huandu/go-assert"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestResponseEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	cases := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", encodingGzip},
+		{"gzip, zstd", encodingZstd},
+		{"gzip;q=1.0, zstd;q=0.5", encodingGzip},
+		{"zstd;q=0, gzip", encodingGzip},
+		{"br, deflate", ""},
+	}
+
+	for _, c := range cases {
+		a.Equal(responseEncoding(c.acceptEncoding), c.encoding)
+	}
+}
+
+func TestCompressResponse(t *testing.T) {
+	a := assert.New(t)
+	name := strings.Repeat("Shana", 10)
+	config := &Config{
+		PkgPrefix: testPkgPrefix,
+		Compression: CompressionConfig{
+			MinSize:       10,
+			ExcludedPaths: []string{"/test-request-info"},
+		},
+	}
+	server := httptest.NewServer(NewRouter(config))
+	defer server.Close()
+
+	for _, encoding := range []string{encodingGzip, encodingZstd} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/test-client-greet?name="+name, nil)
+		a.NilError(err)
+		req.Header.Set("Accept-Encoding", encoding)
+
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		a.Equal(resp.Header.Get("Content-Encoding"), encoding)
+		a.Equal(resp.Header.Get("Vary"), "Accept-Encoding")
+
+		var r io.Reader
+
+		if encoding == encodingGzip {
+			r, err = gzip.NewReader(resp.Body)
+		} else {
+			r, err = zstd.NewReader(resp.Body)
+		}
+
+		a.NilError(err)
+
+		var body struct {
+			Data testClientResponse `json:"data"`
+		}
+		a.NilError(json.NewDecoder(r).Decode(&body))
+		a.Equal(body.Data.Greeting, "Hello, "+name)
+	}
+
+	// Excluded path is not compressed.
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/test-request-info", nil)
+	a.NilError(err)
+	req.Header.Set("Accept-Encoding", encodingGzip)
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	a.NilError(err)
+	defer resp.Body.Close()
+	a.Equal(resp.Header.Get("Content-Encoding"), "")
+
+	// Small response is not compressed with default config.
+	defaultServer := newTestServer()
+	defer defaultServer.Close()
+
+	req, err = http.NewRequest(http.MethodGet, defaultServer.URL+"/test-client-greet?name="+name, nil)
+	a.NilError(err)
+	req.Header.Set("Accept-Encoding", encodingGzip)
+
+	resp, err = http.DefaultTransport.RoundTrip(req)
+	a.NilError(err)
+	defer resp.Body.Close()
+	a.Equal(resp.Header.Get("Content-Encoding"), "")
+}
+
+func TestCompressedRequest(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	gw.Write([]byte(`{"name":"Shana"}`))
+	gw.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/test-client-greet", buf)
+	a.NilError(err)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Content-Encoding", encodingGzip)
+
+	resp, err := http.DefaultClient.Do(req)
+	a.NilError(err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	a.NilError(err)
+	a.Assert(strings.Contains(string(body), "Hello, Shana"))
+}
+
+func TestCompressedRequestTooLarge(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Compression: CompressionConfig{
+			MaxDecompressSize: 1024,
+		},
+	}))
+	defer server.Close()
+
+	zw, err := zstd.NewWriter(nil)
+	a.NilError(err)
+	defer zw.Close()
+	body := []byte(`{"name":"` + strings.Repeat("a", 4096) + `"}`)
+	compressed := map[string][]byte{
+		encodingZstd: zw.EncodeAll(body, nil),
+	}
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	gw.Write(body)
+	gw.Close()
+	compressed[encodingGzip] = buf.Bytes()
+
+	for encoding, data := range compressed {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/test-client-greet", bytes.NewReader(data))
+		a.NilError(err)
+		req.Header.Set("Content-Type", contentTypeJSON)
+		req.Header.Set("Content-Encoding", encoding)
+
+		resp, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+		resp.Body.Close()
+		a.Use(&encoding)
+		a.Equal(resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+
+	// Window larger than the limit is rejected before decoding even if content is small.
+	buf.Reset()
+	zw, err = zstd.NewWriter(buf, zstd.WithSingleSegment(false))
+	a.NilError(err)
+	zw.Write([]byte(`{"name":"Shana"}`))
+	zw.Close()
+	frame := buf.Bytes()
+	frame[5] = 10 << 3 // Window descriptor after magic and frame header descriptor: 1MiB.
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/test-client-greet", bytes.NewReader(frame))
+	a.NilError(err)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Content-Encoding", encodingZstd)
+
+	resp, err := http.DefaultClient.Do(req)
+	a.NilError(err)
+	resp.Body.Close()
+	a.Equal(resp.StatusCode, http.StatusRequestEntityTooLarge)
+}
@@ -14,6 +14,16 @@ type Config struct {
 	IP        string `shana:"ip"`   // The IP to bind. If it's not set, all IPs are bound.
 	Port      int    `shana:"port"` // The port to listen.
 	PkgPrefix string `shana:"-"`    // Filter all exported routes by package prefix.
+
+	TLS         TLSConfig         `shana:"tls"`         // The TLS config. TLS is disabled by default.
+	OpenAPI     OpenAPIConfig     `shana:"openapi"`     // The OpenAPI document endpoint.
+	Compression CompressionConfig `shana:"compression"` // The response compression config.
+	CORS        CORSConfig        `shana:"cors"`        // The CORS policy.
+	Auth        AuthConfig        `shana:"auth"`        // The built-in authenticators.
+	RateLimit   RateLimitConfig   `shana:"rate_limit"`  // The rate limiting rules. Rate limiting is disabled by default.
+	Batch       BatchConfig       `shana:"batch"`       // The batch endpoint. It's disabled by default.
+	WebSocket   WebSocketConfig   `shana:"websocket"`   // The WebSocket endpoint. It's disabled by default.
+	Upload      UploadConfig      `shana:"upload"`      // The limits of multipart/form-data requests.
 }
 
 // Validate validates the config.
@@ -29,6 +39,15 @@ func (c *Config) Validate(ctx context.Context) {
 		errors.Throwf("httpjson: invalid port in config [port=%v]", c.Port)
 		return
 	}
+
+	c.TLS.Validate(ctx)
+	c.Compression.Validate(ctx)
+	c.CORS.Validate(ctx)
+	c.Auth.Validate(ctx)
+	c.RateLimit.Validate(ctx)
+	c.Batch.Validate(ctx)
+	c.WebSocket.Validate(ctx)
+	c.Upload.Validate(ctx)
 }
 
 // Init initializes the config and fills zero values with defaults.
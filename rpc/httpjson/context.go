@@ -0,0 +1,51 @@
+package httpjson
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	irpc "github.com/go-shana/core/internal/rpc"
+	"github.com/go-shana/core/rpc"
+)
+
+const headerRequestID = "X-Request-Id"
+
+func newHandlerInfo(handler *irpc.Handler) *rpc.HandlerInfo {
+	return &rpc.HandlerInfo{
+		Package:  handler.Package,
+		Name:     handler.Name,
+		FuncName: handler.FuncName,
+	}
+}
+
+// newRequestContext returns a context carrying the rpc.RequestInfo of r.
+// The request ID is sent back to client in response header.
+func newRequestContext(w http.ResponseWriter, r *http.Request, info *rpc.HandlerInfo) context.Context {
+	id := r.Header.Get(headerRequestID)
+
+	if id == "" {
+		id = newRequestID()
+	}
+
+	w.Header().Set(headerRequestID, id)
+
+	return rpc.WithRequestInfo(r.Context(), &rpc.RequestInfo{
+		ID:         id,
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Header:     r.Header,
+		Handler:    info,
+	})
+}
+
+func newRequestID() string {
+	var buf [16]byte
+
+	if _, err := rand.Read(buf[:]); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,71 @@
+package httpjson
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testRequestInfoRequest struct{}
+
+type testRequestInfoResponse struct {
+	ID       string `json:"id"`
+	Method   string `json:"method"`
+	Tenant   string `json:"tenant"`
+	FuncName string `json:"funcName"`
+}
+
+func testRequestInfo(ctx context.Context, req *testRequestInfoRequest) (resp *testRequestInfoResponse, err error) {
+	info := rpc.RequestInfoFrom(ctx)
+	resp = &testRequestInfoResponse{
+		ID:       info.ID,
+		Method:   info.Method,
+		Tenant:   info.GetHeader("x-tenant-id"),
+		FuncName: info.Handler.FuncName,
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testRequestInfo)
+}
+
+func TestRequestInfo(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.Header = http.Header{
+		"X-Request-Id": []string{"req-1"},
+		"X-Tenant-Id":  []string{"tenant-1"},
+	}
+	resp, err := Call[testRequestInfoRequest, testRequestInfoResponse](context.Background(), client, "/test-request-info", &testRequestInfoRequest{})
+	a.NilError(err)
+	a.Equal(resp, &testRequestInfoResponse{
+		ID:       "req-1",
+		Method:   http.MethodPost,
+		Tenant:   "tenant-1",
+		FuncName: "testRequestInfo",
+	})
+
+	client.Header = nil
+	resp, err = Call[testRequestInfoRequest, testRequestInfoResponse](context.Background(), client, "/test-request-info", &testRequestInfoRequest{})
+	a.NilError(err)
+	a.Equal(len(resp.ID), 32)
+
+	// Request ID is responded even if request cannot be decoded.
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/test-request-info", strings.NewReader("not json"))
+	a.NilError(err)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set(headerRequestID, "req-2")
+	httpResp, err := http.DefaultClient.Do(req)
+	a.NilError(err)
+	httpResp.Body.Close()
+	a.Equal(httpResp.StatusCode, http.StatusBadRequest)
+	a.Equal(httpResp.Header.Get(headerRequestID), "req-2")
+}
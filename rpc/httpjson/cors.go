@@ -0,0 +1,259 @@
+package httpjson
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-shana/core/errors"
+)
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultCORSHeaders = []string{"Accept", "Authorization", "Content-Type", "X-Request-Id"}
+)
+
+const defaultCORSMaxAge = 24 * time.Hour
+
+// CORSConfig is the config of Cross-Origin Resource Sharing.
+// CORS is disabled if AllowedOrigins is empty.
+//
+// In debug mode, if CORS is not configured, all origins are allowed.
+type CORSConfig struct {
+	// Allowed origins, e.g. "https://example.com".
+	// An origin can contain a wildcard subdomain, e.g. "https://*.example.com".
+	// A single "*" allows all origins. It cannot be used with AllowCredentials.
+	AllowedOrigins []string `shana:"allowed_origins"`
+
+	AllowedHeaders   []string      `shana:"allowed_headers"`   // Allowed request headers. A single "*" allows all headers. Default is "Accept, Authorization, Content-Type, X-Request-Id".
+	AllowedMethods   []string      `shana:"allowed_methods"`   // Allowed methods. Default is "GET, HEAD, POST, PUT, PATCH, DELETE".
+	ExposedHeaders   []string      `shana:"exposed_headers"`   // Response headers exposed to browsers.
+	AllowCredentials bool          `shana:"allow_credentials"` // Allow cookies and other credentials.
+	MaxAge           time.Duration `shana:"max_age"`           // How long preflight results can be cached. Default is 24h.
+}
+
+// Validate validates the config.
+func (c *CORSConfig) Validate(ctx context.Context) {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			// Otherwise, any site can send credentialed requests.
+			if c.AllowCredentials {
+				errors.Throwf("httpjson: CORS origin \"*\" cannot be used with allow_credentials")
+				return
+			}
+
+			continue
+		}
+
+		u, err := url.Parse(origin)
+
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+			errors.Throwf("httpjson: invalid CORS origin [origin=%v]", origin)
+			return
+		}
+	}
+
+	if c.MaxAge < 0 {
+		errors.Throwf("httpjson: invalid CORS max age [max_age=%v]", c.MaxAge)
+		return
+	}
+}
+
+// corsPolicy applies CORSConfig to requests.
+type corsPolicy struct {
+	allowAll         bool
+	origins          map[string]struct{}
+	wildcards        []string // Origins with wildcard subdomains, e.g. "https://.example.com".
+	allowAllHeaders  bool
+	headers          map[string]struct{}
+	methods          map[string]struct{}
+	allowHeaders     string
+	allowMethods     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// newCORSPolicy creates a policy from config.
+// It returns nil if CORS is disabled.
+func newCORSPolicy(config *CORSConfig, debug bool) *corsPolicy {
+	origins := config.AllowedOrigins
+
+	if len(origins) == 0 {
+		if !debug {
+			return nil
+		}
+
+		origins = []string{"*"}
+	}
+
+	headers := config.AllowedHeaders
+	methods := config.AllowedMethods
+	maxAge := config.MaxAge
+
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+
+	if maxAge == 0 {
+		maxAge = defaultCORSMaxAge
+	}
+
+	p := &corsPolicy{
+		origins:          map[string]struct{}{},
+		headers:          map[string]struct{}{},
+		methods:          map[string]struct{}{},
+		exposeHeaders:    strings.Join(config.ExposedHeaders, ", "),
+		allowCredentials: config.AllowCredentials,
+		maxAge:           strconv.Itoa(int(maxAge / time.Second)),
+	}
+
+	for _, origin := range origins {
+		origin = strings.ToLower(origin)
+
+		if origin == "*" {
+			p.allowAll = true
+		} else if strings.Contains(origin, "://*.") {
+			p.wildcards = append(p.wildcards, strings.Replace(origin, "://*.", "://.", 1))
+		} else {
+			p.origins[origin] = struct{}{}
+		}
+	}
+
+	for _, header := range headers {
+		if header == "*" {
+			p.allowAllHeaders = true
+			continue
+		}
+
+		p.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+
+	allowMethods := make([]string, 0, len(methods))
+
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+		allowMethods = append(allowMethods, method)
+		p.methods[method] = struct{}{}
+	}
+
+	// Credentials are never allowed for all origins, e.g. in debug mode.
+	if p.allowAll {
+		p.allowCredentials = false
+	}
+
+	p.allowHeaders = strings.Join(headers, ", ")
+	p.allowMethods = strings.Join(allowMethods, ", ")
+	return p
+}
+
+// isOriginAllowed reports whether origin is allowed.
+func (p *corsPolicy) isOriginAllowed(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+
+	if _, ok := p.origins[origin]; ok {
+		return true
+	}
+
+	for _, wildcard := range p.wildcards {
+		// The wildcard is like "https://.example.com".
+		// Origin "https://a.example.com" matches it while "https://example.com" doesn't.
+		scheme := wildcard[:strings.Index(wildcard, "://")+3]
+		suffix := wildcard[len(scheme):]
+
+		if !strings.HasPrefix(origin, scheme) {
+			continue
+		}
+
+		if host := origin[len(scheme):]; len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *corsPolicy) areHeadersAllowed(headers string) bool {
+	if p.allowAllHeaders {
+		return true
+	}
+
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+
+		if header == "" {
+			continue
+		}
+
+		if _, ok := p.headers[http.CanonicalHeaderKey(header)]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Handle sets CORS headers to w.
+// It returns true if r is a preflight request, which must not be passed to handlers.
+func (p *corsPolicy) Handle(w http.ResponseWriter, r *http.Request) (preflight bool) {
+	origin := r.Header.Get("Origin")
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	preflight = r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	if origin == "" || !p.isOriginAllowed(origin) {
+		return
+	}
+
+	if preflight {
+		method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+
+		if _, ok := p.methods[method]; !ok || !p.areHeadersAllowed(requestHeaders) {
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", p.allowMethods)
+
+		if p.allowAllHeaders {
+			if requestHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", requestHeaders)
+			}
+		} else {
+			header.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		}
+
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	} else if p.exposeHeaders != "" {
+		header.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+	}
+
+	if p.allowAll {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if p.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	return
+}
@@ -0,0 +1,104 @@
+package httpjson
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/huandu/go-assert"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	a := assert.New(t)
+	p := newCORSPolicy(&CORSConfig{
+		AllowedOrigins: []string{"https://example.com", "https://*.shana.dev"},
+	}, false)
+
+	a.Assert(p.isOriginAllowed("https://example.com"))
+	a.Assert(p.isOriginAllowed("HTTPS://EXAMPLE.COM"))
+	a.Assert(!p.isOriginAllowed("http://example.com"))
+	a.Assert(p.isOriginAllowed("https://api.shana.dev"))
+	a.Assert(p.isOriginAllowed("https://a.b.shana.dev"))
+	a.Assert(!p.isOriginAllowed("https://shana.dev"))
+	a.Assert(!p.isOriginAllowed("https://evilshana.dev"))
+
+	a.Assert(newCORSPolicy(&CORSConfig{}, false) == nil)
+	a.Assert(newCORSPolicy(&CORSConfig{}, true).allowAll)
+
+	// Credentials are never allowed for all origins.
+	config := &CORSConfig{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	}
+	validate := func(c *CORSConfig) (err error) {
+		defer errors.Handle(&err)
+		c.Validate(context.Background())
+		return
+	}
+	a.NonNilError(validate(config))
+	a.NilError(validate(&CORSConfig{AllowedOrigins: []string{"*"}}))
+	a.Assert(!newCORSPolicy(config, false).allowCredentials)
+	a.Assert(!newCORSPolicy(&CORSConfig{AllowCredentials: true}, true).allowCredentials)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"https://*.shana.dev"},
+			ExposedHeaders:   []string{"X-Request-Id"},
+			AllowCredentials: true,
+		},
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodOptions, server.URL+"/users/42", nil)
+	a.NilError(err)
+	req.Header.Set("Origin", "https://app.shana.dev")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "content-type, authorization")
+
+	resp, err := http.DefaultClient.Do(req)
+	a.NilError(err)
+	resp.Body.Close()
+
+	a.Equal(resp.StatusCode, http.StatusNoContent)
+	a.Equal(resp.Header.Get("Access-Control-Allow-Origin"), "https://app.shana.dev")
+	a.Equal(resp.Header.Get("Access-Control-Allow-Credentials"), "true")
+	a.Equal(resp.Header.Get("Access-Control-Allow-Methods"), "GET, HEAD, POST, PUT, PATCH, DELETE")
+	a.Equal(resp.Header.Get("Access-Control-Allow-Headers"), "Accept, Authorization, Content-Type, X-Request-Id")
+	a.Equal(resp.Header.Get("Access-Control-Max-Age"), "86400")
+
+	// Disallowed header.
+	req.Header.Set("Access-Control-Request-Headers", "X-Secret")
+	resp, err = http.DefaultClient.Do(req)
+	a.NilError(err)
+	resp.Body.Close()
+
+	a.Equal(resp.StatusCode, http.StatusNoContent)
+	a.Equal(resp.Header.Get("Access-Control-Allow-Origin"), "")
+
+	// Actual request.
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/test-client-greet?name=Shana", nil)
+	a.NilError(err)
+	req.Header.Set("Origin", "https://app.shana.dev")
+
+	resp, err = http.DefaultClient.Do(req)
+	a.NilError(err)
+	resp.Body.Close()
+
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Equal(resp.Header.Get("Access-Control-Allow-Origin"), "https://app.shana.dev")
+	a.Equal(resp.Header.Get("Access-Control-Expose-Headers"), "X-Request-Id")
+
+	// Disallowed origin.
+	req.Header.Set("Origin", "https://example.com")
+	resp, err = http.DefaultClient.Do(req)
+	a.NilError(err)
+	resp.Body.Close()
+
+	a.Equal(resp.Header.Get("Access-Control-Allow-Origin"), "")
+}
@@ -0,0 +1,72 @@
+package httpjson
+
+import (
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/internal/health"
+)
+
+const (
+	pathHealthz = "/_shana/healthz"
+	pathReadyz  = "/_shana/readyz"
+
+	healthOK = "ok"
+)
+
+var (
+	errNotReady  = errors.New("httpjson: service is not ready")
+	errUnhealthy = errors.New("httpjson: health check fails")
+)
+
+// serveHealthz reports whether all health checks pass.
+func serveHealthz(w http.ResponseWriter, r *http.Request) {
+	results, healthy := health.Check(r.Context())
+	writeHealth(w, results, healthy, nil)
+}
+
+// serveReadyz reports whether the service is ready to serve requests and all health checks pass.
+func serveReadyz(w http.ResponseWriter, r *http.Request) {
+	if !health.Ready() {
+		writeHealth(w, nil, false, errNotReady)
+		return
+	}
+
+	results, healthy := health.Check(r.Context())
+	writeHealth(w, results, healthy, nil)
+}
+
+func writeHealth(w http.ResponseWriter, results []health.Result, healthy bool, err error) {
+	checks := make(map[string]string, len(results))
+	resp := &Response{
+		Data: checks,
+	}
+
+	for _, result := range results {
+		if result.Err == nil {
+			checks[result.Name] = healthOK
+		} else {
+			checks[result.Name] = result.Err.Error()
+		}
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Cache-Control", "no-store")
+
+	if err == nil && !healthy {
+		err = errUnhealthy
+	}
+
+	if err == nil {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		resp.Error = err.Error()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	enc := sonic.ConfigDefault.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(resp)
+}
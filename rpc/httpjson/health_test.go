@@ -0,0 +1,53 @@
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/internal/health"
+	"github.com/huandu/go-assert"
+)
+
+func TestHealth(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	var errDB error
+	health.AddCheck("db", func(ctx context.Context) error {
+		return errDB
+	})
+	get := func(path string) (status int, resp map[string]any) {
+		r, err := http.Get(server.URL + path)
+		a.NilError(err)
+		defer r.Body.Close()
+		a.NilError(json.NewDecoder(r.Body).Decode(&resp))
+		return r.StatusCode, resp
+	}
+
+	health.SetReady(false)
+	status, resp := get(pathHealthz)
+	a.Equal(status, http.StatusOK)
+	a.Equal(resp["data"], map[string]any{"db": "ok"})
+	status, resp = get(pathReadyz)
+	a.Equal(status, http.StatusServiceUnavailable)
+	a.Equal(resp["error"], errNotReady.Error())
+
+	health.SetReady(true)
+	defer health.SetReady(false)
+	status, _ = get(pathReadyz)
+	a.Equal(status, http.StatusOK)
+
+	errDB = errors.New("connection refused")
+	defer func() {
+		errDB = nil
+	}()
+	status, resp = get(pathReadyz)
+	a.Equal(status, http.StatusServiceUnavailable)
+	a.Equal(resp["data"], map[string]any{"db": "connection refused"})
+	status, _ = get(pathHealthz)
+	a.Equal(status, http.StatusServiceUnavailable)
+}
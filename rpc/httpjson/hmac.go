@@ -0,0 +1,189 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	hmacAuthPrefix = "HMAC-SHA256 "
+	schemeHMAC     = "hmac"
+
+	defaultHMACMaxSkew     = 5 * time.Minute
+	defaultHMACMaxBodySize = 32 << 20
+)
+
+var (
+	errInvalidSignature = errors.New("httpjson: invalid request signature")
+	errSignatureExpired = errors.New("httpjson: request signature is expired")
+)
+
+// HMACConfig is the config of HMAC authenticator.
+// It's enabled if KeysFile is set.
+//
+// A signed request carries the header:
+//
+//	Authorization: HMAC-SHA256 Credential=<key-id>, Timestamp=<unix-seconds>, Signature=<hex>
+//
+// The signature is the hex encoded HMAC-SHA256 of the following lines joined by "\n":
+// the method, the request URI (path and query), the timestamp and the hex encoded SHA-256 of the body.
+type HMACConfig struct {
+	KeysFile string        `shana:"keys_file"` // The YAML or JSON file containing a map from key ID to secret.
+	MaxSkew  time.Duration `shana:"max_skew"`  // Max difference between timestamp and server time. Default is 5m.
+
+	MaxBodySize int64 `shana:"max_body_size"` // Max size of a signed body in bytes. Default is 32MiB.
+}
+
+// Enabled returns true if HMAC authenticator is configured.
+func (c *HMACConfig) Enabled() bool {
+	return c.KeysFile != ""
+}
+
+// Validate validates the config.
+func (c *HMACConfig) Validate(ctx context.Context) {
+	if c.MaxSkew < 0 {
+		errors.Throwf("httpjson: invalid HMAC max skew [max_skew=%v]", c.MaxSkew)
+		return
+	}
+
+	if c.MaxBodySize < 0 {
+		errors.Throwf("httpjson: invalid HMAC max body size [max_body_size=%v]", c.MaxBodySize)
+		return
+	}
+}
+
+type hmacAuthenticator struct {
+	keys        map[string][]byte
+	maxSkew     time.Duration
+	maxBodySize int64
+	nowFunc     func() time.Time
+}
+
+// NewHMACAuthenticator creates an authenticator verifying HMAC-signed requests.
+// The principal subject is the key ID.
+func NewHMACAuthenticator(config *HMACConfig) (auth Authenticator, err error) {
+	defer errors.Handle(&err)
+
+	config.Validate(context.Background())
+
+	data := errors.Check1(os.ReadFile(config.KeysFile))
+	secrets := map[string]string{}
+	errors.Check(yaml.Unmarshal(data, &secrets))
+
+	if len(secrets) == 0 {
+		errors.Throwf("httpjson: no key in HMAC keys file [file=%v]", config.KeysFile)
+	}
+
+	ha := &hmacAuthenticator{
+		keys:        make(map[string][]byte, len(secrets)),
+		maxSkew:     config.MaxSkew,
+		maxBodySize: config.MaxBodySize,
+		nowFunc:     time.Now,
+	}
+
+	if ha.maxSkew == 0 {
+		ha.maxSkew = defaultHMACMaxSkew
+	}
+
+	if ha.maxBodySize == 0 {
+		ha.maxBodySize = defaultHMACMaxBodySize
+	}
+
+	for id, secret := range secrets {
+		ha.keys[id] = []byte(secret)
+	}
+
+	return ha, nil
+}
+
+func (ha *hmacAuthenticator) Authenticate(r *http.Request) (principal *rpc.Principal, err error) {
+	authorization := r.Header.Get("Authorization")
+
+	if !strings.HasPrefix(authorization, hmacAuthPrefix) {
+		return
+	}
+
+	params := map[string]string{}
+
+	for _, part := range strings.Split(authorization[len(hmacAuthPrefix):], ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		params[k] = v
+	}
+
+	id := params["Credential"]
+	key := ha.keys[id]
+	sig, errSig := hex.DecodeString(params["Signature"])
+	ts, errTs := strconv.ParseInt(params["Timestamp"], 10, 64)
+
+	if key == nil || errSig != nil || errTs != nil {
+		err = errInvalidSignature
+		return
+	}
+
+	if skew := ha.nowFunc().Sub(time.Unix(ts, 0)); skew > ha.maxSkew || skew < -ha.maxSkew {
+		err = errSignatureExpired
+		return
+	}
+
+	bodyHash, err := hashBody(r, ha.maxBodySize)
+
+	if err != nil {
+		return
+	}
+
+	if subtle.ConstantTimeCompare(SignRequest(key, r.Method, r.URL.RequestURI(), ts, bodyHash), sig) != 1 {
+		err = errInvalidSignature
+		return
+	}
+
+	principal = &rpc.Principal{
+		Subject: id,
+		Scheme:  schemeHMAC,
+	}
+	return
+}
+
+// hashBody returns SHA-256 of r.Body and restores the body for later reads.
+// The body cannot be larger than maxSize.
+func hashBody(r *http.Request, maxSize int64) ([]byte, error) {
+	h := sha256.New()
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return h.Sum(nil), nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxSize))
+	r.Body.Close()
+
+	if err != nil {
+		return nil, checkBodySize(err)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	h.Write(body)
+	return h.Sum(nil), nil
+}
+
+// SignRequest returns the HMAC-SHA256 signature of a request.
+// The bodyHash is the SHA-256 of the request body.
+// See HMACConfig for details.
+func SignRequest(key []byte, method, requestURI string, timestamp int64, bodyHash []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%x", method, requestURI, timestamp, bodyHash)
+	return mac.Sum(nil)
+}
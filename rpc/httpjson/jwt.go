@@ -0,0 +1,387 @@
+package httpjson
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+const (
+	jwtHS256 = "HS256"
+	jwtRS256 = "RS256"
+
+	schemeJWT = "jwt"
+)
+
+var (
+	errInvalidToken = errors.New("httpjson: invalid token")
+	errTokenExpired = errors.New("httpjson: token is expired")
+)
+
+// JWTConfig is the config of JWT authenticator.
+// It's enabled if any key file is set.
+type JWTConfig struct {
+	Algorithm     string        `shana:"algorithm"`       // The signing algorithm, either "HS256" or "RS256". Default is "HS256" if SecretFile is set, otherwise "RS256".
+	SecretFile    string        `shana:"secret_file"`     // The file containing the HS256 secret.
+	PublicKeyFile string        `shana:"public_key_file"` // The PEM file containing the RS256 public key or certificate.
+	JWKSFile      string        `shana:"jwks_file"`       // The JWKS file containing RS256 public keys.
+	Issuer        string        `shana:"issuer"`          // If it's set, the "iss" claim must match it.
+	Audience      string        `shana:"audience"`        // If it's set, the "aud" claim must contain it.
+	Leeway        time.Duration `shana:"leeway"`          // Allowed clock skew when checking "exp" and "nbf".
+}
+
+// Enabled returns true if JWT authenticator is configured.
+func (c *JWTConfig) Enabled() bool {
+	return c.SecretFile != "" || c.PublicKeyFile != "" || c.JWKSFile != ""
+}
+
+// Validate validates the config.
+func (c *JWTConfig) Validate(ctx context.Context) {
+	if !c.Enabled() {
+		return
+	}
+
+	switch c.algorithm() {
+	case jwtHS256:
+		if c.SecretFile == "" {
+			errors.Throwf("httpjson: secret_file is required by HS256")
+			return
+		}
+
+	case jwtRS256:
+		if c.PublicKeyFile == "" && c.JWKSFile == "" {
+			errors.Throwf("httpjson: public_key_file or jwks_file is required by RS256")
+			return
+		}
+
+	default:
+		errors.Throwf("httpjson: unsupported JWT algorithm [algorithm=%v]", c.Algorithm)
+		return
+	}
+
+	if c.Leeway < 0 {
+		errors.Throwf("httpjson: invalid JWT leeway [leeway=%v]", c.Leeway)
+		return
+	}
+}
+
+func (c *JWTConfig) algorithm() string {
+	if c.Algorithm != "" {
+		return strings.ToUpper(c.Algorithm)
+	}
+
+	if c.SecretFile != "" {
+		return jwtHS256
+	}
+
+	return jwtRS256
+}
+
+type jwtAuthenticator struct {
+	config  *JWTConfig
+	alg     string
+	secret  []byte
+	keys    map[string]*rsa.PublicKey // Keys by "kid". The key of PublicKeyFile has an empty kid.
+	nowFunc func() time.Time
+}
+
+// NewJWTAuthenticator creates an authenticator verifying JWT in "Authorization: Bearer" header.
+// The principal subject is the "sub" claim.
+func NewJWTAuthenticator(config *JWTConfig) (auth Authenticator, err error) {
+	defer errors.Handle(&err)
+
+	config.Validate(context.Background())
+
+	ja := &jwtAuthenticator{
+		config:  config,
+		alg:     config.algorithm(),
+		keys:    map[string]*rsa.PublicKey{},
+		nowFunc: time.Now,
+	}
+
+	if ja.alg == jwtHS256 {
+		secret := errors.Check1(os.ReadFile(config.SecretFile))
+		ja.secret = []byte(strings.TrimSpace(string(secret)))
+
+		if len(ja.secret) == 0 {
+			errors.Throwf("httpjson: secret file is empty [file=%v]", config.SecretFile)
+		}
+
+		return ja, nil
+	}
+
+	if config.PublicKeyFile != "" {
+		ja.keys[""] = errors.Check1(loadRSAPublicKey(config.PublicKeyFile))
+	}
+
+	if config.JWKSFile != "" {
+		for kid, key := range errors.Check1(loadJWKS(config.JWKSFile)) {
+			ja.keys[kid] = key
+		}
+	}
+
+	return ja, nil
+}
+
+func (ja *jwtAuthenticator) Authenticate(r *http.Request) (principal *rpc.Principal, err error) {
+	authorization := r.Header.Get("Authorization")
+
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return
+	}
+
+	claims, err := ja.verify(strings.TrimSpace(authorization[7:]))
+
+	if err != nil {
+		return
+	}
+
+	sub, _ := claims["sub"].(string)
+	principal = &rpc.Principal{
+		Subject: sub,
+		Scheme:  schemeJWT,
+		Claims:  claims,
+	}
+	return
+}
+
+// verify verifies token and returns its claims.
+func (ja *jwtAuthenticator) verify(token string) (claims map[string]any, err error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		err = errInvalidToken
+		return
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err = decodeJWTPart(parts[0], &header); err != nil {
+		return
+	}
+
+	// Never trust the algorithm chosen by client.
+	if header.Alg != ja.alg {
+		err = errInvalidToken
+		return
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		err = errInvalidToken
+		return
+	}
+
+	signed := token[:len(parts[0])+1+len(parts[1])]
+
+	switch ja.alg {
+	case jwtHS256:
+		mac := hmac.New(sha256.New, ja.secret)
+		mac.Write([]byte(signed))
+
+		if subtle.ConstantTimeCompare(mac.Sum(nil), sig) != 1 {
+			err = errInvalidToken
+			return
+		}
+
+	case jwtRS256:
+		key := ja.keys[header.Kid]
+
+		if key == nil && header.Kid == "" && len(ja.keys) == 1 {
+			for _, k := range ja.keys {
+				key = k
+			}
+		}
+
+		digest := sha256.Sum256([]byte(signed))
+
+		if key == nil || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			err = errInvalidToken
+			return
+		}
+	}
+
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return
+	}
+
+	err = ja.checkClaims(claims)
+	return
+}
+
+func (ja *jwtAuthenticator) checkClaims(claims map[string]any) error {
+	now := ja.nowFunc()
+	leeway := ja.config.Leeway
+
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return errTokenExpired
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0).Add(-leeway)) {
+		return errInvalidToken
+	}
+
+	if iss := ja.config.Issuer; iss != "" && claims["iss"] != iss {
+		return errInvalidToken
+	}
+
+	if aud := ja.config.Audience; aud != "" {
+		switch v := claims["aud"].(type) {
+		case string:
+			if v != aud {
+				return errInvalidToken
+			}
+
+		case []any:
+			found := false
+
+			for _, a := range v {
+				if a == aud {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return errInvalidToken
+			}
+
+		default:
+			return errInvalidToken
+		}
+	}
+
+	return nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+
+	if err != nil {
+		return errInvalidToken
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return errInvalidToken
+	}
+
+	return nil
+}
+
+// loadRSAPublicKey loads a RSA public key from a PEM file.
+// The PEM block can be a PKIX public key, a PKCS #1 public key or a certificate.
+func loadRSAPublicKey(filename string) (key *rsa.PublicKey, err error) {
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return
+	}
+
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		err = fmt.Errorf("httpjson: invalid PEM file [file=%v]", filename)
+		return
+	}
+
+	var pub any
+
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+
+		if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+			return
+		}
+
+		pub = cert.PublicKey
+
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+
+	default:
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+
+	if err != nil {
+		return
+	}
+
+	key, ok := pub.(*rsa.PublicKey)
+
+	if !ok {
+		err = fmt.Errorf("httpjson: public key is not RSA [file=%v]", filename)
+	}
+
+	return
+}
+
+// loadJWKS loads all RSA keys in a JWKS file.
+func loadJWKS(filename string) (keys map[string]*rsa.PublicKey, err error) {
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+
+	if err = json.Unmarshal(data, &jwks); err != nil {
+		return
+	}
+
+	keys = map[string]*rsa.PublicKey{}
+
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		nb, errN := base64.RawURLEncoding.DecodeString(k.N)
+		eb, errE := base64.RawURLEncoding.DecodeString(k.E)
+
+		if errN != nil || errE != nil || len(eb) == 0 || len(eb) > 4 {
+			err = fmt.Errorf("httpjson: invalid RSA key in JWKS [file=%v] [kid=%v]", filename, k.Kid)
+			return
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nb),
+			E: int(new(big.Int).SetBytes(eb).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		err = fmt.Errorf("httpjson: no RSA key in JWKS [file=%v]", filename)
+	}
+
+	return
+}
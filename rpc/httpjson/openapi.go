@@ -0,0 +1,493 @@
+package httpjson
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/data"
+	"github.com/huandu/xstrings"
+)
+
+const (
+	pathOpenAPI    = "/_shana/openapi.json"
+	openAPIVersion = "3.1.0"
+
+	defaultOpenAPITitle   = "Shana service"
+	defaultOpenAPIVersion = "0.0.0"
+)
+
+// OpenAPIConfig is the config of the OpenAPI document endpoint.
+type OpenAPIConfig struct {
+	Enabled bool   `shana:"enabled"` // Serve the OpenAPI document at "/_shana/openapi.json".
+	Title   string `shana:"title"`   // The title of the API. Default is "Shana service".
+	Version string `shana:"version"` // The version of the API. Default is "0.0.0".
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                 `json:"openapi"`
+	Info       openAPIInfo            `json:"info"`
+	Paths      map[string]openAPIPath `json:"paths"`
+	Components openAPIComponents      `json:"components"`
+	Tags       []openAPITag           `json:"tags,omitempty"`
+
+	types   map[reflect.Type]string
+	names   map[string]reflect.Type
+	schemas map[string]*openAPISchema
+	tags    map[string]struct{}
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPITag struct {
+	Name string `json:"name"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas,omitempty"`
+}
+
+// openAPIPath maps lower case HTTP methods to operations.
+type openAPIPath map[string]*openAPIOperation
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Content map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	typeOfTime     = reflect.TypeOf(time.Time{})
+	typeOfDuration = reflect.TypeOf(time.Duration(0))
+	typeOfData     = reflect.TypeOf(data.Data{})
+	typeOfBytes    = reflect.TypeOf([]byte(nil))
+)
+
+// generateOpenAPI generates an OpenAPI document for all handlers in root.
+func generateOpenAPI(config *OpenAPIConfig, root *routeTree) ([]byte, error) {
+	title := config.Title
+	version := config.Version
+
+	if title == "" {
+		title = defaultOpenAPITitle
+	}
+
+	if version == "" {
+		version = defaultOpenAPIVersion
+	}
+
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:   title,
+			Version: version,
+		},
+		Paths:   map[string]openAPIPath{},
+		types:   map[reflect.Type]string{},
+		names:   map[string]reflect.Type{},
+		schemas: map[string]*openAPISchema{},
+		tags:    map[string]struct{}{},
+	}
+
+	root.Walk(func(uri string, handler *routeHandler) {
+		doc.addHandler(uri, handler)
+	})
+
+	doc.Components.Schemas = doc.schemas
+
+	for tag := range doc.tags {
+		doc.Tags = append(doc.Tags, openAPITag{Name: tag})
+	}
+
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+
+	return sonic.ConfigStd.Marshal(doc)
+}
+
+func (doc *openAPIDocument) addHandler(uri string, handler *routeHandler) {
+	fnType := handler.handler.Func.Type()
+	reqType := fnType.In(1).Elem()
+
+	dir := path.Dir(uri)
+	var tags []string
+
+	if dir != "/" {
+		tag := strings.TrimPrefix(dir, "/")
+		tags = []string{tag}
+		doc.tags[tag] = struct{}{}
+	}
+
+	operationID := xstrings.ToCamelCase(strings.NewReplacer("/", "_", "-", "_", "{", "", "}", "").Replace(strings.TrimPrefix(uri, "/")))
+	operationID = strings.ToLower(operationID[:1]) + operationID[1:]
+	summary := handler.handler.FuncName
+	status := "200"
+	var responses map[string]*openAPIResponse
+
+	if s := handler.handler.HTTP.Status; s != 0 {
+		status = strconv.Itoa(s)
+	}
+
+	if item := handler.handler.StreamItem; item != nil {
+		schema := doc.responseSchema(item)
+		responses = map[string]*openAPIResponse{
+			"200": {
+				Description: "Stream of Shana HTTP JSON responses.",
+				Content: map[string]*openAPIMediaType{
+					contentTypeEventStream: {Schema: schema},
+					contentTypeNDJSON:      {Schema: schema},
+				},
+			},
+		}
+	} else {
+		responses = map[string]*openAPIResponse{
+			status: {
+				Description: "Shana HTTP JSON response.",
+				Content: map[string]*openAPIMediaType{
+					"application/json": {
+						Schema: doc.responseSchema(fnType.Out(0).Elem()),
+					},
+				},
+			},
+		}
+	}
+
+	pathParams := doc.pathParameters(uri, reqType)
+	p := doc.Paths[uri]
+
+	if p == nil {
+		p = openAPIPath{}
+		doc.Paths[uri] = p
+	}
+
+	for _, method := range httpMethods(handler.handler) {
+		op := &openAPIOperation{
+			OperationID: operationID,
+			Summary:     summary,
+			Tags:        tags,
+			Responses:   responses,
+		}
+
+		switch {
+		case handler.handler.HTTP.Method == "" && method == http.MethodGet:
+			op.OperationID += "ByQuery"
+		case handler.handler.HTTP.Method != "" && method != http.MethodPost:
+			op.OperationID += xstrings.FirstRuneToUpper(strings.ToLower(method))
+		}
+
+		op.Parameters = append(op.Parameters, pathParams...)
+		op.Parameters = append(op.Parameters, doc.boundParameters(reqType)...)
+
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete {
+			op.Parameters = append(op.Parameters, doc.queryParameters(reqType, pathParams)...)
+		} else {
+			op.RequestBody = &openAPIRequestBody{
+				Content: map[string]*openAPIMediaType{
+					"application/json": {
+						Schema: doc.schema(reqType),
+					},
+				},
+			}
+
+			if hasFileFields(reqType) {
+				op.RequestBody.Content[contentTypeMultipart] = &openAPIMediaType{
+					Schema: doc.schema(reqType),
+				}
+			}
+		}
+
+		p[strings.ToLower(method)] = op
+	}
+}
+
+// pathParameters returns path parameters in uri.
+// The schema of a parameter is the schema of the request field with the same name.
+func (doc *openAPIDocument) pathParameters(uri string, t reflect.Type) (params []*openAPIParameter) {
+	props := doc.properties(t)
+
+	for _, seg := range strings.Split(uri, "/") {
+		if len(seg) < 2 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+			continue
+		}
+
+		name := seg[1 : len(seg)-1]
+		schema := props[name]
+
+		if field := findBoundField(t, bindPath, name); field != nil {
+			schema = doc.schema(field.typ)
+		}
+
+		if schema == nil {
+			schema = &openAPISchema{Type: "string"}
+		}
+
+		params = append(params, &openAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	return
+}
+
+// responseSchema wraps the schema of t with the Response envelope.
+func (doc *openAPIDocument) responseSchema(t reflect.Type) *openAPISchema {
+	return &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"code":    {},
+			"message": {Type: "string"},
+			"error":   {Type: "string"},
+			"data":    doc.schema(t),
+		},
+	}
+}
+
+// queryParameters returns query parameters for all fields of t except the ones in excluded.
+func (doc *openAPIDocument) queryParameters(t reflect.Type, excluded []*openAPIParameter) (params []*openAPIParameter) {
+	props := doc.properties(t)
+	names := make([]string, 0, len(props))
+
+	for name := range props {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if isParameterIncluded(excluded, name) {
+			continue
+		}
+
+		params = append(params, &openAPIParameter{
+			Name:   name,
+			In:     "query",
+			Schema: props[name],
+		})
+	}
+
+	return
+}
+
+// boundParameters returns header and cookie parameters for bound fields of t.
+func (doc *openAPIDocument) boundParameters(t reflect.Type) (params []*openAPIParameter) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, field := range boundFields(t) {
+		if field.source == bindPath {
+			continue
+		}
+
+		params = append(params, &openAPIParameter{
+			Name:   field.name,
+			In:     field.source,
+			Schema: doc.schema(field.typ),
+		})
+	}
+
+	return
+}
+
+func isParameterIncluded(params []*openAPIParameter, name string) bool {
+	for _, param := range params {
+		if param.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// properties returns all properties in the schema of t.
+func (doc *openAPIDocument) properties(t reflect.Type) map[string]*openAPISchema {
+	schema := doc.schema(t)
+
+	if schema.Ref != "" {
+		schema = doc.schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+
+	return schema.Properties
+}
+
+// schema returns the schema of t.
+// Named struct types are stored in components and referenced by $ref.
+func (doc *openAPIDocument) schema(t reflect.Type) *openAPISchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case typeOfTime:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case typeOfDuration:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case typeOfData:
+		return &openAPISchema{Type: "object"}
+	case typeOfBytes:
+		return &openAPISchema{Type: "string", Format: "byte"}
+	case typeOfFile.Elem():
+		return &openAPISchema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Array, reflect.Slice:
+		return &openAPISchema{Type: "array", Items: doc.schema(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: doc.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.structSchema(t)
+		}
+
+		name, ok := doc.types[t]
+
+		if !ok {
+			name = doc.schemaName(t)
+			doc.types[t] = name
+			doc.names[name] = t
+			doc.schemas[name] = doc.structSchema(t)
+		}
+
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	}
+
+	// Any value.
+	return &openAPISchema{}
+}
+
+func (doc *openAPIDocument) structSchema(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{
+		Type:       "object",
+		Properties: map[string]*openAPISchema{},
+	}
+	doc.addFields(schema, t)
+	return schema
+}
+
+// addFields adds fields of t to schema following the rules of encoding/json.
+func (doc *openAPIDocument) addFields(schema *openAPISchema, t reflect.Type) {
+	num := t.NumField()
+
+	for i := 0; i < num; i++ {
+		field := t.Field(i)
+		tag := data.ParseFieldTag(field.Tag.Get("json"))
+
+		if tag.Skipped {
+			continue
+		}
+
+		if field.Anonymous && tag.Alias == "" {
+			ft := field.Type
+
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				doc.addFields(schema, ft)
+				continue
+			}
+		}
+
+		if !field.IsExported() || isBoundField(field) {
+			continue
+		}
+
+		name := field.Name
+
+		if tag.Alias != "" {
+			name = tag.Alias
+		}
+
+		schema.Properties[name] = doc.schema(field.Type)
+	}
+}
+
+// schemaName returns a unique component name for t.
+func (doc *openAPIDocument) schemaName(t reflect.Type) string {
+	pkg := path.Base(t.PkgPath())
+	name := t.Name()
+
+	if pkg != "" && pkg != "." {
+		name = pkg + "." + name
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+
+		return '_'
+	}, name)
+
+	unique := name
+
+	for i := 2; ; i++ {
+		if _, ok := doc.names[unique]; !ok {
+			return unique
+		}
+
+		unique = name + "_" + strconv.Itoa(i)
+	}
+}
@@ -0,0 +1,51 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestOpenAPI(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		OpenAPI: OpenAPIConfig{
+			Enabled: true,
+			Title:   "Test",
+		},
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + pathOpenAPI)
+	a.NilError(err)
+	defer resp.Body.Close()
+
+	doc := map[string]any{}
+	a.NilError(json.NewDecoder(resp.Body).Decode(&doc))
+	a.Equal(doc["openapi"], "3.1.0")
+	a.Equal(doc["info"].(map[string]any)["title"], "Test")
+
+	paths := doc["paths"].(map[string]any)
+	op := paths["/test-client-greet"].(map[string]any)["post"].(map[string]any)
+	a.Equal(op["operationId"], "testClientGreet")
+	a.Equal(op["summary"], "testClientGreet")
+
+	get := paths["/test-client-greet"].(map[string]any)["get"].(map[string]any)
+	params := get["parameters"].([]any)
+	a.Equal(len(params), 1)
+	a.Equal(params[0].(map[string]any)["name"], "name")
+
+	put := paths["/users/{id}"].(map[string]any)["put"].(map[string]any)
+	a.Equal(put["operationId"], "usersIdPut")
+	a.Assert(put["responses"].(map[string]any)["201"] != nil)
+	a.Equal(put["parameters"].([]any)[0].(map[string]any)["in"], "path")
+	a.Assert(paths["/users/{id}"].(map[string]any)["get"] != nil)
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	greeting := schemas["httpjson.testClientResponse"].(map[string]any)["properties"].(map[string]any)["greeting"]
+	a.Equal(greeting, map[string]any{"type": "string"})
+}
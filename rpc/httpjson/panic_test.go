@@ -0,0 +1,68 @@
+package httpjson
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	irpc "github.com/go-shana/core/internal/rpc"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+func testPanicHandler(ctx context.Context, req *testClientRequest) (resp *testClientResponse, err error) {
+	var m map[string]int
+	m[req.Name] = 1
+	return
+}
+
+func TestPanicStack(t *testing.T) {
+	a := assert.New(t)
+
+	var reported errors.PanicError
+	errors.SetPanicSink(func(err errors.PanicError) {
+		reported = err
+	})
+	defer errors.SetPanicSink(nil)
+
+	handler := rpc.Compile(testPanicHandler)
+	_, err := handler(context.Background(), &testClientRequest{Name: "Shana"})
+	a.NonNilError(err)
+	a.Assert(reported != nil)
+	a.Assert(strings.Contains(reported.Stack(), "testPanicHandler"))
+
+	resp := newResponse(&irpc.Handler{FuncName: "testPanicHandler"}, nil, err, true)
+	a.Assert(len(resp.Debug.Stack) > 0)
+	a.Assert(strings.HasPrefix(resp.Debug.Stack[0], testPkgPrefix+".testPanicHandler ("))
+
+	for _, line := range resp.Debug.Stack {
+		a.Use(&line)
+		a.Assert(!strings.HasPrefix(line, "runtime.") && !strings.HasPrefix(line, "reflect."))
+	}
+
+	resp = newResponse(&irpc.Handler{FuncName: "testPanicHandler"}, nil, err, false)
+	a.Assert(resp.Debug == nil)
+}
+
+func TestIsUserFrame(t *testing.T) {
+	a := assert.New(t)
+
+	cases := []struct {
+		fn, file string
+		user     bool
+	}{
+		{"main.main", "/app/main.go", true},
+		{"net/http.HandlerFunc.ServeHTTP", "/go/src/net/http/server.go", false},
+		{"runtime.gopanic", "/go/src/runtime/panic.go", false},
+		{"example.com/app/user.(*Service).Get", "/app/user/service.go", true},
+		{"github.com/go-shana/core/rpc.compile[...].func1", "/core/rpc/compile.go", false},
+		{"github.com/go-shana/core/rpc/httpjson.testPanicHandler", "/core/rpc/httpjson/panic_test.go", true},
+	}
+
+	for _, c := range cases {
+		a.Use(&c)
+		a.Equal(isUserFrame(&runtime.Frame{Function: c.fn, File: c.file}), c.user)
+	}
+}
@@ -0,0 +1,253 @@
+package httpjson
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+const (
+	rateLimitKeyRoute     = "route"
+	rateLimitKeyIP        = "ip"
+	rateLimitKeyPrincipal = "principal"
+	rateLimitKeyHeader    = "header:"
+
+	// Max buckets in a rate limiter.
+	// When it's reached, the least recently used bucket is reused if it's idle.
+	maxBuckets = 10000
+)
+
+// ErrRateLimited is the error returned to clients when a request exceeds the rate limit.
+var ErrRateLimited = errors.NewErrorCode(http.StatusTooManyRequests, "httpjson: too many requests")
+
+// RateLimitConfig is the config of token-bucket rate limiting.
+//
+// The Default rule applies to every route unless it's overridden in Routes.
+type RateLimitConfig struct {
+	Default RateLimitRule            `shana:"default"` // The rule for all routes.
+	Routes  map[string]RateLimitRule `shana:"routes"`  // Rules by route path, e.g. "/foo/bar" or "/users/{id}".
+}
+
+// RateLimitRule is a token-bucket rate limiting rule.
+type RateLimitRule struct {
+	Rate  float64 `shana:"rate"`  // Requests per second. The route is not limited if it's 0.
+	Burst int     `shana:"burst"` // Max requests in a burst. Default is Rate rounded up.
+
+	// The key to group clients in separated buckets.
+	//
+	//   - "route" or empty: all clients share the same bucket.
+	//   - "ip": the client IP.
+	//   - "principal": the authenticated principal. It falls back to "ip" if request is not authenticated.
+	//   - "header:<name>": the value of header. It falls back to "ip" if the header is empty.
+	//     The header should be set by a trusted proxy, as clients can use a new bucket with a new value.
+	Key string `shana:"key"`
+}
+
+// Validate validates the config.
+func (c *RateLimitConfig) Validate(ctx context.Context) {
+	c.Default.Validate(ctx)
+
+	for path, rule := range c.Routes {
+		if path == "" || path[0] != '/' {
+			errors.Throwf("httpjson: rate limit route must start with '/' [path=%v]", path)
+			return
+		}
+
+		rule.Validate(ctx)
+	}
+}
+
+// Validate validates the rule.
+func (r *RateLimitRule) Validate(ctx context.Context) {
+	if r.Rate < 0 || math.IsNaN(r.Rate) || math.IsInf(r.Rate, 0) {
+		errors.Throwf("httpjson: invalid rate limit rate [rate=%v]", r.Rate)
+		return
+	}
+
+	if r.Burst < 0 {
+		errors.Throwf("httpjson: invalid rate limit burst [burst=%v]", r.Burst)
+		return
+	}
+
+	switch key := r.Key; {
+	case key == "", key == rateLimitKeyRoute, key == rateLimitKeyIP, key == rateLimitKeyPrincipal:
+	case strings.HasPrefix(key, rateLimitKeyHeader) && len(key) > len(rateLimitKeyHeader):
+	default:
+		errors.Throwf("httpjson: invalid rate limit key [key=%v]", key)
+		return
+	}
+}
+
+// newRateLimiters creates rate limiters for all routes in root.
+// Routes without limit are not in the result.
+func newRateLimiters(config *RateLimitConfig, root *routeTree) (limiters map[*routeHandler]*RateLimiter, err error) {
+	limiters = map[*routeHandler]*RateLimiter{}
+	found := map[string]bool{}
+
+	root.Walk(func(uri string, handler *routeHandler) {
+		rule, ok := config.Routes[uri]
+
+		if ok {
+			found[uri] = true
+		} else {
+			rule = config.Default
+		}
+
+		if rule.Rate > 0 {
+			limiters[handler] = NewRateLimiter(&rule)
+		}
+	})
+
+	for path := range config.Routes {
+		if !found[path] {
+			err = fmt.Errorf("httpjson: rate limit route is not found [path=%v]", path)
+			return
+		}
+	}
+
+	return
+}
+
+// RateLimiter is a token-bucket rate limiter grouping clients by the key of a RateLimitRule.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	key   string
+
+	mu         sync.Mutex
+	maxBuckets int
+	buckets    map[string]*list.Element // Values are *tokenBucket.
+	lru        *list.List               // Buckets from the most to the least recently used.
+	overflow   *tokenBucket             // Shared by new clients when all buckets are in use.
+}
+
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with rule.
+// The rule.Rate must be positive.
+func NewRateLimiter(rule *RateLimitRule) *RateLimiter {
+	burst := rule.Burst
+
+	if burst == 0 {
+		burst = int(math.Ceil(rule.Rate))
+	}
+
+	return &RateLimiter{
+		rate:       rule.Rate,
+		burst:      float64(burst),
+		key:        rule.Key,
+		maxBuckets: maxBuckets,
+		buckets:    map[string]*list.Element{},
+		lru:        list.New(),
+		overflow: &tokenBucket{
+			tokens: float64(burst),
+		},
+	}
+}
+
+// clientKey returns the bucket key of r.
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	switch key := rl.key; {
+	case key == "", key == rateLimitKeyRoute:
+		return ""
+
+	case key == rateLimitKeyPrincipal:
+		if p := rpc.PrincipalFrom(r.Context()); p != nil {
+			return "principal:" + p.Scheme + ":" + p.Subject
+		}
+
+	case strings.HasPrefix(key, rateLimitKeyHeader):
+		if v := r.Header.Get(key[len(rateLimitKeyHeader):]); v != "" {
+			return "header:" + v
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// Allow takes a token for r at now.
+// If there is no token, it returns false and the duration to wait for next token.
+func (rl *RateLimiter) Allow(r *http.Request, now time.Time) (ok bool, retryAfter time.Duration) {
+	key := rl.clientKey(r)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b := rl.bucket(key, now)
+	b.refill(now, rl.rate, rl.burst)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	retryAfter = time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	return false, retryAfter
+}
+
+// bucket returns the bucket of key.
+//
+// If there are too many buckets, the least recently used bucket is reused if it's idle.
+// Otherwise, the overflow bucket is returned, so that clients cannot bypass the limit with new keys.
+func (rl *RateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	if elem := rl.buckets[key]; elem != nil {
+		rl.lru.MoveToFront(elem)
+		return elem.Value.(*tokenBucket)
+	}
+
+	if rl.lru.Len() < rl.maxBuckets {
+		b := &tokenBucket{
+			key:    key,
+			tokens: rl.burst,
+			last:   now,
+		}
+		rl.buckets[key] = rl.lru.PushFront(b)
+		return b
+	}
+
+	elem := rl.lru.Back()
+	b := elem.Value.(*tokenBucket)
+
+	// A full bucket is the same as a new one.
+	if b.refill(now, rl.rate, rl.burst); b.tokens < rl.burst {
+		return rl.overflow
+	}
+
+	delete(rl.buckets, b.key)
+	b.key = key
+	rl.buckets[key] = elem
+	rl.lru.MoveToFront(elem)
+	return b
+}
+
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+}
+
+// retryAfterHeader returns the Retry-After header value in seconds.
+func retryAfterHeader(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
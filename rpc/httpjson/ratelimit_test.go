@@ -0,0 +1,116 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	a := assert.New(t)
+	rl := NewRateLimiter(&RateLimitRule{
+		Rate:  2,
+		Burst: 2,
+		Key:   "header:X-Api-Key",
+	})
+	now := time.Now()
+	newRequest := func(key string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", key)
+		return req
+	}
+
+	ok, _ := rl.Allow(newRequest("a"), now)
+	a.Assert(ok)
+	ok, _ = rl.Allow(newRequest("a"), now)
+	a.Assert(ok)
+	ok, retryAfter := rl.Allow(newRequest("a"), now)
+	a.Assert(!ok)
+	a.Equal(retryAfter, 500*time.Millisecond)
+
+	// Different key uses a different bucket.
+	ok, _ = rl.Allow(newRequest("b"), now)
+	a.Assert(ok)
+
+	// Tokens are refilled over time.
+	ok, _ = rl.Allow(newRequest("a"), now.Add(500*time.Millisecond))
+	a.Assert(ok)
+	ok, _ = rl.Allow(newRequest("a"), now.Add(500*time.Millisecond))
+	a.Assert(!ok)
+
+	// The least recently used bucket is reused only if it's idle.
+	// Otherwise, new clients share the overflow bucket.
+	rl.maxBuckets = 2
+	ok, _ = rl.Allow(newRequest("c"), now.Add(500*time.Millisecond))
+	a.Assert(ok)
+	a.Assert(rl.buckets["header:b"] == nil)
+
+	for _, key := range []string{"d", "e"} {
+		ok, _ = rl.Allow(newRequest(key), now.Add(500*time.Millisecond))
+		a.Assert(ok)
+	}
+
+	ok, _ = rl.Allow(newRequest("f"), now.Add(500*time.Millisecond))
+	a.Assert(!ok)
+	a.Equal(len(rl.buckets), 2)
+	a.Assert(rl.buckets["header:d"] == nil)
+
+	ok, _ = rl.Allow(newRequest("d"), now.Add(time.Hour))
+	a.Assert(ok)
+	a.Equal(len(rl.buckets), 2)
+	a.Assert(rl.buckets["header:a"] == nil)
+	a.Assert(rl.buckets["header:d"] != nil)
+}
+
+func TestRateLimitRouter(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		RateLimit: RateLimitConfig{
+			Routes: map[string]RateLimitRule{
+				"/test-client-greet": {
+					Rate: 0.5,
+					Key:  rateLimitKeyIP,
+				},
+			},
+		},
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/test-client-greet?name=Shana")
+	a.NilError(err)
+	resp.Body.Close()
+	a.Equal(resp.StatusCode, http.StatusOK)
+
+	resp, err = http.Get(server.URL + "/test-client-greet?name=Shana")
+	a.NilError(err)
+	defer resp.Body.Close()
+	a.Equal(resp.StatusCode, http.StatusTooManyRequests)
+	a.Equal(resp.Header.Get("Retry-After"), "2")
+
+	body := &Response{}
+	a.NilError(json.NewDecoder(resp.Body).Decode(body))
+	a.Equal(body.Code, float64(http.StatusTooManyRequests))
+	a.Equal(body.Message, ErrRateLimited.Error())
+
+	// Other routes are not limited.
+	for i := 0; i < 3; i++ {
+		resp, err = http.Get(server.URL + "/test-request-info")
+		a.NilError(err)
+		resp.Body.Close()
+		a.Equal(resp.StatusCode, http.StatusOK)
+	}
+
+	root, err := parseRoute(&Config{PkgPrefix: testPkgPrefix}, nil)
+	a.NilError(err)
+	_, err = newRateLimiters(&RateLimitConfig{
+		Routes: map[string]RateLimitRule{
+			"/not-found": {Rate: 1},
+		},
+	}, root)
+	a.NonNilError(err)
+}
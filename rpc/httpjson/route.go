@@ -1,25 +1,27 @@
 package httpjson
 
 import (
-	"encoding/json"
+	"bytes"
+	"fmt"
+	"mime"
 	"net/http"
 	"net/url"
 	"reflect"
+	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/bytedance/sonic"
-	"github.com/go-shana/core/data"
 	"github.com/go-shana/core/errors"
-	"github.com/go-shana/core/initer"
 	"github.com/go-shana/core/internal/global"
 	"github.com/go-shana/core/internal/rpc"
-	"github.com/go-shana/core/validator"
 )
 
 // routeTree is a HTTP JSON route.
 type routeTree struct {
 	handlers  routeHandlerMap
 	subRoutes routeMap
+	templates templateRoutes // Routes with customized path templates. Only used in root.
 }
 
 type routeHandler struct {
@@ -39,7 +41,7 @@ func newRoute() *routeTree {
 }
 
 // Lookup finds a handler by path.
-func (r *routeTree) Lookup(path string) http.HandlerFunc {
+func (r *routeTree) Lookup(path string) *routeHandler {
 	if path == "" {
 		return nil
 	}
@@ -68,22 +70,65 @@ func (r *routeTree) Lookup(path string) http.HandlerFunc {
 		}
 	}
 
-	handler := route.handlers[handlerName]
+	return route.handlers[handlerName]
+}
 
-	if handler == nil {
-		return nil
+// Walk calls f for every handler in r and its sub routes.
+// The uri is the full path of the handler.
+// Handlers with path templates are visited once with their templates as uri.
+func (r *routeTree) Walk(f func(uri string, handler *routeHandler)) {
+	r.walk("", f)
+
+	visited := map[*routeHandler]struct{}{}
+
+	for _, tr := range r.templates {
+		if _, ok := visited[tr.handler]; ok {
+			continue
+		}
+
+		visited[tr.handler] = struct{}{}
+		f(tr.pattern, tr.handler)
+	}
+}
+
+func (r *routeTree) walk(parent string, f func(uri string, handler *routeHandler)) {
+	for name, handler := range r.handlers {
+		f(parent+"/"+name, handler)
 	}
 
-	return handler.handlerFunc
+	for path, tree := range r.subRoutes {
+		tree.walk(parent+"/"+path, f)
+	}
 }
 
-func parseRoute(config *Config, handlers []*rpc.Handler) (root *routeTree) {
+func parseRoute(config *Config, handlers []*rpc.Handler) (root *routeTree, err error) {
 	pkgPrefix := config.PkgPrefix
 	root = newRoute()
 
 	for _, handler := range handlers {
+		rh := &routeHandler{
+			handler: handler,
+		}
+
+		if pattern := handler.HTTP.Path; pattern != "" {
+			rh.handlerFunc = parseHandlerFunc(config, handler, pattern)
+
+			for _, method := range httpMethods(handler) {
+				var tr *templateRoute
+
+				if tr, err = parseTemplate(method, pattern, rh); err != nil {
+					return
+				}
+
+				if err = root.templates.Add(tr); err != nil {
+					return
+				}
+			}
+
+			continue
+		}
+
 		paths := parsePackage(pkgPrefix, handler.Package)
-		fn := parseHandlerFunc(config, handler)
 		r := root
 		m := root.subRoutes
 		ok := false
@@ -99,16 +144,147 @@ func parseRoute(config *Config, handlers []*rpc.Handler) (root *routeTree) {
 			m = r.subRoutes
 		}
 
-		r.handlers[handler.Name] = &routeHandler{
-			handler:     handler,
-			handlerFunc: fn,
+		path := "/" + strings.Join(append(paths, handler.Name), "/")
+
+		if existing := r.handlers[handler.Name]; existing != nil {
+			err = fmt.Errorf("httpjson: duplicated route [path=%v] [handlers=%v, %v]",
+				path, fullFuncName(existing.handler), fullFuncName(handler))
+			return
 		}
+
+		rh.handlerFunc = parseHandlerFunc(config, handler, path)
+		r.handlers[handler.Name] = rh
+	}
+
+	if err = checkRouteConflicts(root, ""); err != nil {
+		return
+	}
+
+	if err = checkTemplateConflicts(root); err != nil {
+		return
 	}
 
 	sonic.Pretouch(reflect.TypeOf(Response{}))
 	return
 }
 
+// checkRouteConflicts reports an error if a handler name is the same as a sub route path.
+func checkRouteConflicts(r *routeTree, parent string) error {
+	for name, handler := range r.handlers {
+		if _, ok := r.subRoutes[name]; ok {
+			return fmt.Errorf("httpjson: handler name conflicts with sub-package path [path=%v] [handler=%v]",
+				parent+"/"+name, fullFuncName(handler.handler))
+		}
+	}
+
+	for path, tree := range r.subRoutes {
+		if err := checkRouteConflicts(tree, parent+"/"+path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkTemplateConflicts reports an error if a path template is hidden by a route derived from package and name.
+// Derived routes are matched before templates, so a template without parameters is never reached
+// if there is a derived route with the same path.
+func checkTemplateConflicts(root *routeTree) error {
+	for _, tr := range root.templates {
+		if !tr.isLiteral() {
+			continue
+		}
+
+		if rh := root.Lookup(tr.pattern); rh != nil {
+			return fmt.Errorf("httpjson: path template conflicts with route [path=%v %v] [handlers=%v, %v]",
+				tr.method, tr.pattern, fullFuncName(rh.handler), fullFuncName(tr.handler.handler))
+		}
+	}
+
+	return nil
+}
+
+// checkBuiltinConflicts reports an error if any route matches the path of a builtin endpoint.
+// Builtin endpoints are matched before all routes.
+func checkBuiltinConflicts(root *routeTree, builtins map[string]http.HandlerFunc) error {
+	paths := make([]string, 0, len(builtins))
+
+	for path := range builtins {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if rh := root.Lookup(path); rh != nil {
+			return fmt.Errorf("httpjson: route conflicts with builtin endpoint [path=%v] [handler=%v]",
+				path, fullFuncName(rh.handler))
+		}
+
+		segments := strings.Split(strings.Trim(path, "/"), "/")
+
+		for _, tr := range root.templates {
+			if _, ok := tr.match(segments); ok {
+				return fmt.Errorf("httpjson: path template conflicts with builtin endpoint [path=%v] [template=%v %v] [handler=%v]",
+					path, tr.method, tr.pattern, fullFuncName(tr.handler.handler))
+			}
+		}
+	}
+
+	return nil
+}
+
+func fullFuncName(handler *rpc.Handler) string {
+	return handler.Package + "." + handler.FuncName
+}
+
+// httpMethods returns all HTTP methods allowed by handler.
+func httpMethods(handler *rpc.Handler) []string {
+	if handler.HTTP.Method == "" {
+		return []string{http.MethodGet, http.MethodPost}
+	}
+
+	return []string{handler.HTTP.Method}
+}
+
+// isMethodAllowed reports whether method is in methods.
+// HEAD is allowed if GET is allowed.
+func isMethodAllowed(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method || (method == http.MethodHead && m == http.MethodGet) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowHeader returns the Allow header value with all methods.
+// HEAD and OPTIONS are added automatically.
+func allowHeader(methods []string) string {
+	allowed := make([]string, 0, len(methods)+2)
+	seen := map[string]struct{}{}
+	add := func(method string) {
+		if _, ok := seen[method]; ok {
+			return
+		}
+
+		seen[method] = struct{}{}
+		allowed = append(allowed, method)
+	}
+
+	for _, method := range methods {
+		add(method)
+
+		if method == http.MethodGet {
+			add(http.MethodHead)
+		}
+	}
+
+	add(http.MethodOptions)
+	return strings.Join(allowed, ", ")
+}
+
 // parsePackage stripes pkgPrefix from pkg and separate pkg with '/'.
 func parsePackage(pkgPrefix, pkg string) []string {
 	path := pkg[len(pkgPrefix):]
@@ -156,55 +332,46 @@ type Response struct {
 // DebugInfo contains more debug information.
 type DebugInfo struct {
 	FuncName string   `json:"funcName,omitempty"`
+	Codec    string   `json:"codec,omitempty"` // The content type of response.
 	Errors   []string `json:"errors,omitempty"`
+	Stack    []string `json:"stack,omitempty"` // The stack trace of a panic in user code.
 }
 
-func parseHandlerFunc(config *Config, handler *rpc.Handler) http.HandlerFunc {
+// parseHandlerFunc creates a http.HandlerFunc for handler served at path.
+func parseHandlerFunc(config *Config, handler *rpc.Handler, path string) http.HandlerFunc {
+	if handler.StreamItem != nil {
+		return parseStreamHandlerFunc(config, handler)
+	}
+
 	fn := handler.Func
 	fnType := fn.Type()
 	reqType := fnType.In(1).Elem()
 	respType := fnType.Out(0).Elem()
 	debug := global.Debug()
+	info := newHandlerInfo(handler)
+	status := handler.HTTP.Status
+	compression := &config.Compression
+	compress := compression.isEnabled(path)
+
+	if status == 0 {
+		status = http.StatusOK
+	}
 
 	sonic.Pretouch(reqType)
 	sonic.Pretouch(respType)
 
-	handleFunc := func(w http.ResponseWriter, r *http.Request) (respVal reflect.Value, err error) {
+	handleFunc := func(w http.ResponseWriter, r *http.Request, contentType string) (respVal reflect.Value, err error) {
 		defer errors.Handle(&err)
+		defer removeUploadedFiles(r)
 
 		reqVal := reflect.New(reqType)
-		req := reqVal.Interface()
 		respHeader := w.Header()
 
-		respHeader.Set("Content-Type", "application/json; charset=utf-8")
-
-		if debug {
-			setCORSHeaders(respHeader)
-		}
-
-		switch r.Method {
-		case http.MethodGet:
-			errors.Check(unmarshalQueryString(reqVal, r.URL))
-
-		case http.MethodPost:
-			errors.Check(unmarshalQueryString(reqVal, r.URL))
-
-			if r.Body != nil {
-				dec := sonic.ConfigDefault.NewDecoder(r.Body)
-				errors.Check(dec.Decode(req))
-				r.Body.Close()
-			}
-
-		default:
-			w.WriteHeader(405)
-			return
-		}
+		respHeader.Set("Content-Type", contentTypeHeader(contentType))
 
-		// TODO: add more context information.
-		ctx := r.Context()
-
-		errors.Check(validator.Validate(ctx, req))
-		errors.Check(initer.Init(ctx, req))
+		// Request ID must be set before decoding so that decode errors carry it too.
+		ctx := newRequestContext(w, r, info)
+		checkDecodeRequest(reqVal, r, config)
 
 		ret := fn.Call([]reflect.Value{reflect.ValueOf(ctx), reqVal})
 		errors.Assert(len(ret) == 2)
@@ -219,110 +386,196 @@ func parseHandlerFunc(config *Config, handler *rpc.Handler) http.HandlerFunc {
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		respVal, err := handleFunc(w, r)
-		resp := &Response{}
-
-		var code any
-		var key error
-		var errs []error
-		var msg string
+		contentType, codec := responseCodec(r.Header.Get("Accept"))
+		respVal, err := handleFunc(w, r, contentType)
+		var data any
 
 		if err == nil && !respVal.IsValid() {
 			err = errors.New("httpjson: invalid response value")
 		}
 
+		if respVal.IsValid() {
+			data = respVal.Interface()
+		}
+
+		resp := newResponse(handler, data, err, debug)
+
+		if resp.Debug != nil {
+			resp.Debug.Codec = contentType
+		}
+
+		code := status
+
 		if err != nil {
-			if he, ok := err.(errors.HandlerError); ok {
-				key = he.KeyError()
-				msg = key.Error()
+			code = errorStatus(err)
+		}
 
-				if debug {
-					errs = he.Unwrap()
-				}
-			} else {
-				key = err
-				msg = err.Error()
-
-				if debug {
-					if e := errors.Unwrap(err); e != nil {
-						errs = []error{e}
-					}
-				}
+		buf := &bytes.Buffer{}
+
+		if err := codec.Encode(buf, resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBody(w, r, compression, compress, code, buf.Bytes())
+	}
+}
+
+// newResponse creates a Response with data and err.
+func newResponse(handler *rpc.Handler, data any, err error, debug bool) *Response {
+	resp := &Response{
+		Data: data,
+	}
+
+	var code any
+	var key error
+	var errs []error
+	var msg string
+
+	if err != nil {
+		if he, ok := err.(errors.HandlerError); ok {
+			key = he.KeyError()
+			msg = key.Error()
+
+			if debug {
+				errs = he.Unwrap()
 			}
+		} else {
+			key = err
+			msg = err.Error()
 
-			if codeFunc := reflect.ValueOf(key).MethodByName("Code"); codeFunc.IsValid() {
-				if t := codeFunc.Type(); t.Kind() == reflect.Func && t.NumIn() == 0 && t.NumOut() == 1 {
-					if ret := codeFunc.Call(nil)[0]; ret.IsValid() {
-						code = ret.Interface()
-					}
+			if debug {
+				if e := errors.Unwrap(err); e != nil {
+					errs = []error{e}
 				}
 			}
 		}
 
-		if respVal.IsValid() {
-			resp.Data = respVal.Interface()
-		}
+		code = errorCode(key)
+	}
 
-		if code == nil {
-			resp.Error = msg
-		} else {
-			resp.Code = code
-			resp.Message = msg
+	if code == nil {
+		resp.Error = msg
+	} else {
+		resp.Code = code
+		resp.Message = msg
+	}
+
+	if debug {
+		errStrs := make([]string, len(errs))
+
+		for i, e := range errs {
+			errStrs[i] = e.Error()
 		}
 
-		if debug {
-			errStrs := make([]string, len(errs))
+		resp.Debug = &DebugInfo{
+			FuncName: handler.FuncName,
+			Errors:   errStrs,
+		}
 
-			for i, e := range errs {
-				errStrs[i] = e.Error()
-			}
+		var pe errors.PanicError
 
-			resp.Debug = &DebugInfo{
-				FuncName: handler.FuncName,
-				Errors:   errStrs,
-			}
+		if errors.As(err, &pe) {
+			resp.Debug.Stack = userStack(pe.Frames())
 		}
+	}
+
+	return resp
+}
+
+const corePkgPrefix = "github.com/go-shana/core/"
 
-		enc := sonic.ConfigDefault.NewEncoder(w)
-		enc.SetEscapeHTML(false)
+// userStack formats frames in user code.
+// Frames in standard packages and Shana core packages are removed.
+func userStack(frames []runtime.Frame) []string {
+	stack := make([]string, 0, len(frames))
 
-		if debug {
-			enc.SetIndent("", "  ")
+	for _, frame := range frames {
+		if !isUserFrame(&frame) {
+			continue
 		}
 
-		enc.Encode(resp)
+		stack = append(stack, fmt.Sprintf("%v (%v:%v)", frame.Function, frame.File, frame.Line))
 	}
+
+	return stack
 }
 
-func setCORSHeaders(header http.Header) {
-	header.Set("Access-Control-Allow-Origin", "*")
-	header.Set("Access-Control-Allow-Headers", "Content-Type")
-	header.Set("Access-Control-Allow-Methods", "GET, POST")
-	header.Set("Access-Control-Max-Age", "86400")
+func isUserFrame(frame *runtime.Frame) bool {
+	fn := frame.Function
+
+	if strings.HasPrefix(fn, corePkgPrefix) {
+		// Tests in core packages are considered as user code.
+		return strings.HasSuffix(frame.File, "_test.go")
+	}
+
+	// Standard packages don't have any dot in the first path element.
+	// Package "main" is always user code.
+	pkg := fn
+
+	if slash := strings.LastIndexByte(pkg, '/'); slash >= 0 {
+		if dot := strings.IndexByte(pkg[slash:], '.'); dot >= 0 {
+			pkg = pkg[:slash+dot]
+		}
+	} else if dot := strings.IndexByte(pkg, '.'); dot >= 0 {
+		pkg = pkg[:dot]
+	}
+
+	if pkg == "main" {
+		return true
+	}
+
+	first, _, _ := strings.Cut(pkg, "/")
+	return strings.Contains(first, ".")
 }
 
-func unmarshalQueryString(ptr reflect.Value, u *url.URL) (err error) {
+// decodeRequest decodes query string, body and path parameters of r to the request pointed by ptr.
+// Path parameters take precedence over body and body takes precedence over query string.
+// Fields with `header`, `cookie` or `path` tags are only set by headers, cookies or path parameters.
+//
+// The body is decoded by the codec matching the Content-Type header.
+// Form and multipart bodies are decoded as form values and files.
+// The body is optional except in POST requests.
+func decodeRequest(ptr reflect.Value, r *http.Request, config *Config) (err error) {
 	defer errors.Handle(&err)
-	values := u.Query()
-	val := ptr.Elem()
-	errors.Assert(val.Kind() == reflect.Struct)
 
-	raw := data.RawData{}
+	errors.Check(unmarshalQueryString(ptr, r.URL))
 
-	for k, vs := range values {
-		l := len(vs)
-		if l == 0 {
-			continue
-		}
+	if hasBody(r) {
+		contentType := r.Header.Get("Content-Type")
+		errors.Check(decompressBody(r, config.Compression.maxDecompressSize()))
+		defer r.Body.Close()
 
-		raw[k] = json.Number(vs[l-1])
+		switch mt, _, _ := mime.ParseMediaType(contentType); mt {
+		case contentTypeForm:
+			errors.Check(checkBodySize(r.ParseForm()))
+			errors.Check(unmarshalValues(ptr, r.PostForm))
+		case contentTypeMultipart:
+			errors.Check(decodeMultipart(ptr, r, &config.Upload))
+		default:
+			_, codec := requestCodec(contentType)
+			errors.Check(checkBodySize(codec.Decode(r.Body, ptr.Interface())))
+		}
 	}
 
-	d := data.Make(raw)
-	dec := &data.Decoder{
-		TagName: "json",
+	if params := pathParamsFrom(r.Context()); len(params) != 0 {
+		values := make(url.Values, len(params))
+
+		for k, v := range params {
+			values.Set(k, v)
+		}
+
+		errors.Check(unmarshalValues(ptr, values))
 	}
-	errors.Check(dec.Decode(d, ptr.Interface()))
 
+	errors.Check(bindFields(ptr, r))
 	return
 }
+
+func hasBody(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Body == nil {
+		return false
+	}
+
+	return r.Method == http.MethodPost || r.ContentLength != 0
+}
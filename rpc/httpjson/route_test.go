@@ -0,0 +1,73 @@
+package httpjson
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	irpc "github.com/go-shana/core/internal/rpc"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+func TestParseRouteConflicts(t *testing.T) {
+	a := assert.New(t)
+	config := &Config{
+		PkgPrefix: "example.com/app",
+	}
+	fn := reflect.ValueOf(rpc.Compile(testClientGreet))
+	newHandler := func(pkg, name, funcName string) *irpc.Handler {
+		return &irpc.Handler{
+			Package:  pkg,
+			Name:     name,
+			FuncName: funcName,
+			Func:     fn,
+		}
+	}
+
+	_, err := parseRoute(config, []*irpc.Handler{
+		newHandler("example.com/app/user", "get", "Get"),
+		newHandler("example.com/app/user/profile", "get", "Get"),
+	})
+	a.NilError(err)
+
+	_, err = parseRoute(config, []*irpc.Handler{
+		newHandler("example.com/app/user", "get", "Get"),
+		newHandler("example.com/app/user", "get", "GET"),
+	})
+	a.NonNilError(err)
+	a.Assert(strings.Contains(err.Error(), "example.com/app/user.Get, example.com/app/user.GET"))
+
+	_, err = parseRoute(config, []*irpc.Handler{
+		newHandler("example.com/app", "user", "User"),
+		newHandler("example.com/app/user", "get", "Get"),
+	})
+	a.NonNilError(err)
+	a.Assert(strings.Contains(err.Error(), "[path=/user]"))
+
+	// Template without parameters is hidden by derived route.
+	template := newHandler("example.com/app/user", "me", "Me")
+	template.HTTP = irpc.HTTPRoute{Method: "GET", Path: "/user/me"}
+	_, err = parseRoute(config, []*irpc.Handler{
+		newHandler("example.com/app/user", "me", "Me"),
+		template,
+	})
+	a.NonNilError(err)
+	a.Assert(strings.Contains(err.Error(), "[path=GET /user/me]"))
+
+	template.HTTP.Path = "/user/{id}"
+	root, err := parseRoute(config, []*irpc.Handler{
+		newHandler("example.com/app/user", "me", "Me"),
+		template,
+	})
+	a.NilError(err)
+	a.NilError(checkBuiltinConflicts(root, map[string]http.HandlerFunc{pathHealthz: serveHealthz}))
+
+	template.HTTP.Path = "/_shana/{name}"
+	root, err = parseRoute(config, []*irpc.Handler{template})
+	a.NilError(err)
+	err = checkBuiltinConflicts(root, map[string]http.HandlerFunc{pathHealthz: serveHealthz})
+	a.NonNilError(err)
+	a.Assert(strings.Contains(err.Error(), "[path=/_shana/healthz]"))
+}
@@ -4,41 +4,175 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
+	"time"
 
-	"github.com/go-shana/core/internal/rpc"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/internal/global"
+	irpc "github.com/go-shana/core/internal/rpc"
+	"github.com/go-shana/core/rpc"
+)
+
+var (
+	errNotFound         = errors.New("httpjson: route is not found")
+	errMethodNotAllowed = errors.New("httpjson: method is not allowed")
 )
 
 // Router is a HTTP JSON router.
 type Router struct {
-	root *routeTree
+	root     *routeTree
+	builtins map[string]http.HandlerFunc
+	cors     *corsPolicy
+	auth     authChain
+	limiters map[*routeHandler]*RateLimiter
+
+	websockets wsConnSet
 }
 
 var _ http.Handler = new(Router)
 
 // NewRouter creates a new HTTP JSON router.
+// It panics if any two handlers share the same route or a route is hidden by a builtin endpoint.
 func NewRouter(config *Config) *Router {
 	pkgPrefix := config.PkgPrefix
-	registry := rpc.DefaultRegistry()
+	registry := irpc.DefaultRegistry()
 	handlers := registry.Handlers(pkgPrefix)
-	root := parseRoute(config, handlers)
+	root := errors.Check1(parseRoute(config, handlers))
 
 	printRouteTree(root, "")
 
-	return &Router{
+	router := &Router{
 		root: root,
+		builtins: map[string]http.HandlerFunc{
+			pathHealthz: serveHealthz,
+			pathReadyz:  serveReadyz,
+		},
+		cors: newCORSPolicy(&config.CORS, global.Debug()),
+		auth: errors.Check1(newAuthChain(&config.Auth)),
+	}
+
+	router.limiters = errors.Check1(newRateLimiters(&config.RateLimit, root))
+
+	if config.Batch.Enabled {
+		router.builtins[config.Batch.path()] = router.serveBatch(config)
+	}
+
+	if config.WebSocket.Enabled {
+		router.builtins[config.WebSocket.path()] = router.serveWebSocket(config)
+	}
+
+	if config.OpenAPI.Enabled {
+		doc := errors.Check1(generateOpenAPI(&config.OpenAPI, root))
+		router.builtins[pathOpenAPI] = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write(doc)
+		}
 	}
+
+	errors.Check(checkBuiltinConflicts(root, router.builtins))
+
+	return router
 }
 
 // ServeHTTP implements http.Handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	handlerFunc := r.root.Lookup(req.URL.Path)
+	if r.cors != nil && r.cors.Handle(w, req) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if builtin := r.builtins[req.URL.Path]; builtin != nil {
+		if isDispatched(req) {
+			writeError(w, req, http.StatusBadRequest, errInvalidCall)
+			return
+		}
+
+		builtin(w, req)
+		return
+	}
+
+	var allowed []string
+
+	if rh := r.root.Lookup(req.URL.Path); rh != nil {
+		methods := httpMethods(rh.handler)
+
+		if isMethodAllowed(methods, req.Method) {
+			r.serve(rh, w, req)
+			return
+		}
+
+		allowed = methods
+	} else {
+		var tr *templateRoute
+		var params map[string]string
+		tr, params, allowed = r.root.templates.Lookup(req.Method, req.URL.Path)
+
+		if tr != nil {
+			r.serve(tr.handler, w, withPathParams(req, params))
+			return
+		}
+	}
 
-	if handlerFunc == nil {
-		http.NotFound(w, req)
+	if len(allowed) == 0 {
+		writeError(w, req, http.StatusNotFound, errNotFound)
 		return
 	}
 
-	handlerFunc(w, req)
+	w.Header().Set("Allow", allowHeader(allowed))
+
+	if req.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeError(w, req, http.StatusMethodNotAllowed, errMethodNotAllowed)
+}
+
+// serve authenticates req, applies rate limit and calls the handler.
+func (r *Router) serve(rh *routeHandler, w http.ResponseWriter, req *http.Request) {
+	// Streaming handler cannot be called in batch or WebSocket.
+	// Reject it before calling handler to avoid any side effect.
+	if rh.handler.StreamItem != nil && isDispatched(req) {
+		writeError(w, req, http.StatusBadRequest, errUnsupportedCall)
+		return
+	}
+
+	// A batch entry may have been authenticated with the batch request.
+	if len(r.auth) != 0 && !rh.handler.Public && rpc.PrincipalFrom(req.Context()) == nil {
+		principal, err := r.auth.Authenticate(req)
+
+		if err != nil {
+			writeError(w, req, http.StatusUnauthorized, err)
+			return
+		}
+
+		req = req.WithContext(rpc.WithPrincipal(req.Context(), principal))
+	}
+
+	if limiter := r.limiters[rh]; limiter != nil {
+		if ok, retryAfter := limiter.Allow(req, time.Now()); !ok {
+			w.Header().Set("Retry-After", retryAfterHeader(retryAfter))
+			writeError(w, req, http.StatusTooManyRequests, ErrRateLimited)
+			return
+		}
+	}
+
+	rh.handlerFunc(w, req)
+}
+
+// Close closes all WebSocket connections, which are not closed by http.Server on shutdown.
+// New WebSocket connections are refused after Close.
+func (r *Router) Close() {
+	r.websockets.Close()
+}
+
+// writeError writes a Response with err to w.
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	contentType, codec := responseCodec(r.Header.Get("Accept"))
+	w.Header().Set("Content-Type", contentTypeHeader(contentType))
+	w.WriteHeader(status)
+
+	codec.Encode(w, newResponse(nil, nil, err, false))
 }
 
 func printRouteTree(root *routeTree, parent string) {
@@ -50,13 +184,23 @@ func printRouteTree(root *routeTree, parent string) {
 			maxLen = len(path)
 		}
 
-		uris = append(uris, []string{parent + "/" + path, handler.handler.FuncName})
+		uris = append(uris, []string{strings.Join(httpMethods(handler.handler), "|"), parent + "/" + path, handler.handler.FuncName})
+	}
+
+	if parent == "" {
+		for _, tr := range root.templates {
+			if len(tr.pattern) > maxLen {
+				maxLen = len(tr.pattern)
+			}
+
+			uris = append(uris, []string{tr.method, tr.pattern, tr.handler.handler.FuncName})
+		}
 	}
 
 	lines := make([]string, 0, len(uris))
 
 	for _, uri := range uris {
-		lines = append(lines, fmt.Sprintf("GET|POST\t%-[1]*[2]s => %[3]s", maxLen+len(parent)+1, uri[0], uri[1]))
+		lines = append(lines, fmt.Sprintf("%[1]s\t%-[2]*[3]s => %[4]s", uri[0], maxLen+len(parent)+1, uri[1], uri[2]))
 	}
 
 	sort.Strings(lines)
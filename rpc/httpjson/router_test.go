@@ -0,0 +1,91 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/huandu/go-assert"
+)
+
+func TestRouterMethods(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	do := func(method, path string) (*http.Response, *Response) {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		a.NilError(err)
+		resp, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		a.NilError(err)
+
+		if len(body) == 0 {
+			return resp, nil
+		}
+
+		r := &Response{}
+		a.NilError(json.Unmarshal(body, r))
+		return resp, r
+	}
+
+	resp, r := do(http.MethodGet, "/not-found")
+	a.Equal(resp.StatusCode, http.StatusNotFound)
+	a.Equal(resp.Header.Get("Content-Type"), "application/json; charset=utf-8")
+	a.Equal(r.Error, errNotFound.Error())
+
+	resp, r = do(http.MethodDelete, "/test-client-greet")
+	a.Equal(resp.StatusCode, http.StatusMethodNotAllowed)
+	a.Equal(resp.Header.Get("Allow"), "GET, HEAD, POST, OPTIONS")
+	a.Equal(r.Error, errMethodNotAllowed.Error())
+
+	resp, r = do(http.MethodOptions, "/test-client-greet")
+	a.Equal(resp.StatusCode, http.StatusNoContent)
+	a.Equal(resp.Header.Get("Allow"), "GET, HEAD, POST, OPTIONS")
+	a.Assert(r == nil)
+
+	resp, r = do(http.MethodOptions, "/users/42")
+	a.Equal(resp.StatusCode, http.StatusNoContent)
+	a.Equal(resp.Header.Get("Allow"), "GET, HEAD, PUT, OPTIONS")
+	a.Assert(r == nil)
+
+	resp, r = do(http.MethodHead, "/test-client-greet?name=Shana")
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Assert(resp.ContentLength > 0)
+	a.Assert(r == nil)
+
+	resp, r = do(http.MethodHead, "/users/42")
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Assert(r == nil)
+}
+
+func TestWriteError(t *testing.T) {
+	a := assert.New(t)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	cases := []struct {
+		err  error
+		body map[string]any
+	}{
+		{errNotFound, map[string]any{"error": errNotFound.Error()}},
+		{ErrRateLimited, map[string]any{"code": float64(http.StatusTooManyRequests), "message": ErrRateLimited.Error()}},
+		{errors.NewErrorCode("denied", "access denied"), map[string]any{"code": "denied", "message": "access denied"}},
+		{errors.Join(errRequestTooLarge, errors.New("http: request body too large")), map[string]any{"error": errRequestTooLarge.Error()}},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		writeError(rec, req, http.StatusUnauthorized, c.err)
+
+		var body map[string]any
+		a.NilError(json.Unmarshal(rec.Body.Bytes(), &body))
+		a.Use(&c)
+		a.Equal(rec.Code, http.StatusUnauthorized)
+		a.Equal(body, c.body)
+	}
+}
@@ -13,6 +13,7 @@ import (
 // Server is a HTTP JSON server.
 type Server struct {
 	config *Config
+	router *Router
 	server *http.Server
 }
 
@@ -24,6 +25,7 @@ func NewServer(config *Config) *Server {
 	addr := fmt.Sprintf("%v:%v", config.IP, config.Port)
 	return &Server{
 		config: config,
+		router: router,
 		server: &http.Server{
 			Addr:    addr,
 			Handler: router,
@@ -36,7 +38,13 @@ func (s *Server) Serve(ctx context.Context) error {
 	// TODO: use logger instead of fmt.
 	fmt.Printf("Server is starting at address %v\n", s.server.Addr)
 
-	err := s.server.ListenAndServe()
+	var err error
+
+	if s.config.TLS.Enabled() {
+		err = s.serveTLS()
+	} else {
+		err = s.server.ListenAndServe()
+	}
 
 	if errors.Is(err, http.ErrServerClosed) {
 		return nil
@@ -47,5 +55,17 @@ func (s *Server) Serve(ctx context.Context) error {
 
 // Shutdown stops the server.
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.router.Close()
 	return s.server.Shutdown(ctx)
 }
+
+func (s *Server) serveTLS() error {
+	reloader, err := newTLSReloader(&s.config.TLS)
+
+	if err != nil {
+		return err
+	}
+
+	s.server.TLSConfig = reloader.TLSConfig()
+	return s.server.ListenAndServeTLS("", "")
+}
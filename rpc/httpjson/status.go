@@ -0,0 +1,135 @@
+package httpjson
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+// HTTPStatusError is an error declaring its HTTP status.
+type HTTPStatusError interface {
+	error
+
+	HTTPStatus() int
+}
+
+// statusError is an error responded in status.
+type statusError struct {
+	status int
+	msg    string
+}
+
+var _ HTTPStatusError = new(statusError)
+
+func newStatusError(status int, msg string) *statusError {
+	return &statusError{
+		status: status,
+		msg:    msg,
+	}
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func (e *statusError) HTTPStatus() int {
+	return e.status
+}
+
+var (
+	errorStatusMu sync.RWMutex
+	errorStatuses = map[any]int{}
+)
+
+// RegisterErrorStatus responds errors with code, e.g. errors.ErrorCode[T], in HTTP status.
+// The status must be in range [100, 599].
+//
+//	var errNotFound = errors.NewErrorCode(1404, "item is not found")
+//
+//	func init() {
+//	    httpjson.RegisterErrorStatus(1404, http.StatusNotFound)
+//	}
+func RegisterErrorStatus[T comparable](code T, status int) {
+	if status < 100 || status > 599 {
+		errors.Throwf("httpjson: invalid HTTP status [code=%v] [status=%v]", code, status)
+		return
+	}
+
+	errorStatusMu.Lock()
+	defer errorStatusMu.Unlock()
+
+	errorStatuses[code] = status
+}
+
+// checkDecodeRequest decodes r to ptr and throws error on failure.
+// The error is joined with rpc.ErrInvalidRequest so that it's responded in 400.
+func checkDecodeRequest(ptr reflect.Value, r *http.Request, config *Config) {
+	if err := decodeRequest(ptr, r, config); err != nil {
+		errors.Throw(err, rpc.ErrInvalidRequest)
+	}
+}
+
+// errorStatus returns the HTTP status responding err.
+//
+// The status is decided in following order.
+//
+//   - The key error implements HTTPStatusError.
+//   - The code of the key error is registered by RegisterErrorStatus.
+//   - Request cannot be decoded, validated or initialized: 400.
+//   - The key error has a code: 200, as the code is a business error handled by client.
+//   - Otherwise, e.g. panic: 500.
+func errorStatus(err error) int {
+	key := err
+
+	if he, ok := err.(errors.HandlerError); ok {
+		key = he.KeyError()
+	}
+
+	if se, ok := key.(HTTPStatusError); ok {
+		return se.HTTPStatus()
+	}
+
+	code := errorCode(key)
+
+	if code != nil && reflect.TypeOf(code).Comparable() {
+		errorStatusMu.RLock()
+		status, ok := errorStatuses[code]
+		errorStatusMu.RUnlock()
+
+		if ok {
+			return status
+		}
+	}
+
+	if errors.Is(err, rpc.ErrInvalidRequest) {
+		return http.StatusBadRequest
+	}
+
+	if code != nil {
+		return http.StatusOK
+	}
+
+	return http.StatusInternalServerError
+}
+
+// errorCode returns the code of err if err has a method `Code() T`.
+func errorCode(err error) any {
+	codeFunc := reflect.ValueOf(err).MethodByName("Code")
+
+	if !codeFunc.IsValid() {
+		return nil
+	}
+
+	if t := codeFunc.Type(); t.NumIn() != 0 || t.NumOut() != 1 {
+		return nil
+	}
+
+	if ret := codeFunc.Call(nil)[0]; ret.IsValid() {
+		return ret.Interface()
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testStatusRequest struct {
+	Kind string `json:"kind"`
+}
+
+func (req *testStatusRequest) Validate(ctx context.Context) {
+	if req.Kind == "invalid" {
+		errors.Throw(errTestStatusInvalidKind)
+	}
+}
+
+type testStatusResponse struct{}
+
+type testStatusError struct{}
+
+func (testStatusError) Error() string   { return "teapot" }
+func (testStatusError) HTTPStatus() int { return http.StatusTeapot }
+
+var (
+	errTestStatusInvalidKind = errors.NewErrorCode(1100, "invalid kind")
+	errTestStatusNotFound    = errors.NewErrorCode(1101, "not found")
+	errTestStatusUnmapped    = errors.NewErrorCode(1102, "unmapped")
+)
+
+func testStatus(ctx context.Context, req *testStatusRequest) (resp *testStatusResponse, err error) {
+	switch req.Kind {
+	case "not-found":
+		err = errTestStatusNotFound
+	case "unmapped":
+		err = errTestStatusUnmapped
+	case "teapot":
+		err = testStatusError{}
+	case "panic":
+		panic("boom")
+	default:
+		resp = &testStatusResponse{}
+	}
+
+	return
+}
+
+func init() {
+	rpc.Export(testStatus)
+	RegisterErrorStatus(1101, http.StatusNotFound)
+}
+
+func TestErrorStatus(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+	}))
+	defer server.Close()
+
+	errors.SetPanicSink(func(errors.PanicError) {})
+	defer errors.SetPanicSink(nil)
+
+	cases := []struct {
+		body   string
+		status int
+		resp   map[string]any
+	}{
+		{`{"kind": "ok"}`, http.StatusOK, map[string]any{"data": map[string]any{}}},
+		{`{"kind": "not-found"}`, http.StatusNotFound, map[string]any{"code": float64(1101), "message": "not found", "data": nil}},
+		{`{"kind": "unmapped"}`, http.StatusOK, map[string]any{"code": float64(1102), "message": "unmapped", "data": nil}},
+		{`{"kind": "teapot"}`, http.StatusTeapot, map[string]any{"error": "teapot", "data": nil}},
+		{`{"kind": "invalid"}`, http.StatusBadRequest, map[string]any{"code": float64(1100), "message": "invalid kind", "data": nil}},
+		{`{"kind": 123}`, http.StatusBadRequest, nil},
+		{`{"kind": "panic"}`, http.StatusInternalServerError, nil},
+	}
+
+	for _, c := range cases {
+		resp, err := http.Post(server.URL+"/test-status", contentTypeJSON, strings.NewReader(c.body))
+		a.NilError(err)
+
+		var body map[string]any
+		a.NilError(json.NewDecoder(resp.Body).Decode(&body))
+		resp.Body.Close()
+
+		a.Use(&c)
+		a.Equal(resp.StatusCode, c.status)
+
+		if c.resp != nil {
+			a.Equal(body, c.resp)
+		} else {
+			a.NotEqual(body["error"], "")
+		}
+	}
+}
+
+func TestRegisterErrorStatus(t *testing.T) {
+	a := assert.New(t)
+
+	for _, status := range []int{0, 99, 600, 999} {
+		err := func() (err error) {
+			defer errors.Handle(&err)
+			RegisterErrorStatus(1199, status)
+			return
+		}()
+		a.Use(&status)
+		a.NonNilError(err)
+	}
+
+	a.NilError(func() (err error) {
+		defer errors.Handle(&err)
+		RegisterErrorStatus(1199, 599)
+		return
+	}())
+}
@@ -0,0 +1,169 @@
+package httpjson
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/internal/global"
+	"github.com/go-shana/core/internal/rpc"
+)
+
+const (
+	contentTypeEventStream = "text/event-stream"
+	contentTypeNDJSON      = "application/x-ndjson"
+)
+
+// streamWriter writes Response to client one by one.
+type streamWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	sse     bool
+	written bool // Whether any response is written.
+	buf     bytes.Buffer
+}
+
+func newStreamWriter(w http.ResponseWriter, r *http.Request) *streamWriter {
+	sse := acceptsMediaType(r.Header.Get("Accept"), contentTypeEventStream)
+	header := w.Header()
+
+	if sse {
+		header.Set("Content-Type", contentTypeEventStream+"; charset=utf-8")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+	} else {
+		header.Set("Content-Type", contentTypeNDJSON+"; charset=utf-8")
+	}
+
+	flusher, _ := w.(http.Flusher)
+	return &streamWriter{
+		w:       w,
+		flusher: flusher,
+		sse:     sse,
+	}
+}
+
+// Write writes resp to client and flushes it immediately.
+func (sw *streamWriter) Write(resp *Response) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	return sw.write(resp, false)
+}
+
+// WriteError writes resp as an "error" event in SSE.
+// If nothing is written yet, the response is sent in status.
+func (sw *streamWriter) WriteError(resp *Response, status int) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if !sw.written {
+		sw.w.WriteHeader(status)
+	}
+
+	return sw.write(resp, true)
+}
+
+func (sw *streamWriter) write(resp *Response, isError bool) error {
+	buf := &sw.buf
+	buf.Reset()
+
+	if sw.sse {
+		if isError {
+			buf.WriteString("event: error\n")
+		}
+
+		buf.WriteString("data: ")
+	}
+
+	enc := sonic.ConfigDefault.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(resp); err != nil {
+		return err
+	}
+
+	if sw.sse {
+		buf.WriteByte('\n')
+	}
+
+	sw.written = true
+
+	if _, err := sw.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+
+	return nil
+}
+
+func parseStreamHandlerFunc(config *Config, handler *rpc.Handler) http.HandlerFunc {
+	fn := handler.Func
+	reqType := fn.Type().In(1).Elem()
+	debug := global.Debug()
+	info := newHandlerInfo(handler)
+
+	sonic.Pretouch(reqType)
+	sonic.Pretouch(handler.StreamItem)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := newStreamWriter(w, r)
+		err := func() (err error) {
+			defer errors.Handle(&err)
+			defer removeUploadedFiles(r)
+
+			reqVal := reflect.New(reqType)
+			ctx := newRequestContext(w, r, info)
+			checkDecodeRequest(reqVal, r, config)
+			send := rpc.SendFunc(func(item any) error {
+				// Stop sending as soon as client goes away.
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				return sw.Write(&Response{Data: item})
+			})
+
+			ret := fn.Call([]reflect.Value{reflect.ValueOf(ctx), reqVal, reflect.ValueOf(send)})
+			errors.Assert(len(ret) == 1)
+
+			if errVal := ret[0]; errVal.IsValid() && !errVal.IsNil() {
+				err = errVal.Interface().(error)
+			}
+
+			return
+		}()
+
+		if err == nil || r.Context().Err() != nil {
+			return
+		}
+
+		sw.WriteError(newResponse(handler, nil, err, debug), errorStatus(err))
+	}
+}
+
+// acceptsMediaType reports whether the Accept header explicitly accepts mediaType.
+func acceptsMediaType(accept, mediaType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+
+		if err != nil {
+			continue
+		}
+
+		if mt == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package httpjson
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testStreamRequest struct {
+	Count int `json:"count"`
+}
+
+type testStreamItem struct {
+	Index int `json:"index"`
+}
+
+func testStreamCount(ctx context.Context, req *testStreamRequest, stream rpc.Stream[testStreamItem]) error {
+	if req.Count < 0 {
+		return errors.New("negative count")
+	}
+
+	for i := 0; i < req.Count; i++ {
+		if err := stream.Send(&testStreamItem{Index: i}); err != nil {
+			return err
+		}
+	}
+
+	if req.Count > 2 {
+		return errors.NewErrorCode(1002, "too many items")
+	}
+
+	return nil
+}
+
+func init() {
+	rpc.ExportStream(testStreamCount)
+}
+
+func testStreamGet(t *testing.T, url, accept string) (status int, contentType, body string) {
+	a := assert.New(t)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	a.NilError(err)
+	req.Header.Set("Accept", accept)
+
+	resp, err := http.DefaultClient.Do(req)
+	a.NilError(err)
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	a.NilError(err)
+	return resp.StatusCode, resp.Header.Get("Content-Type"), string(content)
+}
+
+func TestStream(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	status, contentType, body := testStreamGet(t, server.URL+"/test-stream-count?count=2", "application/x-ndjson")
+	a.Equal(status, http.StatusOK)
+	a.Assert(strings.HasPrefix(contentType, contentTypeNDJSON))
+	a.Equal(body, `{"data":{"index":0}}`+"\n"+`{"data":{"index":1}}`+"\n")
+
+	status, contentType, body = testStreamGet(t, server.URL+"/test-stream-count?count=3", "text/event-stream")
+	a.Equal(status, http.StatusOK)
+	a.Assert(strings.HasPrefix(contentType, contentTypeEventStream))
+	a.Equal(body, "data: "+`{"data":{"index":0}}`+"\n\n"+
+		"data: "+`{"data":{"index":1}}`+"\n\n"+
+		"data: "+`{"data":{"index":2}}`+"\n\n"+
+		"event: error\ndata: "+`{"code":1002,"message":"too many items"}`+"\n\n")
+
+	// Errors before any item is sent are responded in the status of error.
+	status, _, body = testStreamGet(t, server.URL+"/test-stream-count?count=abc", "application/x-ndjson")
+	a.Equal(status, http.StatusBadRequest)
+	a.Assert(strings.Contains(body, `"error"`))
+
+	status, _, body = testStreamGet(t, server.URL+"/test-stream-count?count=-1", "application/x-ndjson")
+	a.Equal(status, http.StatusInternalServerError)
+	a.Equal(body, `{"error":"negative count"}`+"\n")
+}
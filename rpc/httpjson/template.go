@@ -0,0 +1,197 @@
+package httpjson
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// templateRoute is a route with customized method and path template, e.g. "PUT /users/{id}".
+type templateRoute struct {
+	method   string
+	pattern  string
+	segments []string // Literal segments. It's empty if the segment is a parameter.
+	params   []string // Parameter names. It's empty if the segment is a literal.
+	handler  *routeHandler
+}
+
+type templateRoutes []*templateRoute
+
+type pathParamsKey struct{}
+
+// parseTemplate parses a path template like "/users/{id}/posts/{post_id}".
+func parseTemplate(method, pattern string, handler *routeHandler) (*templateRoute, error) {
+	if pattern == "" || pattern[0] != '/' {
+		return nil, fmt.Errorf("httpjson: path template must start with '/' [path=%v]", pattern)
+	}
+
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	route := &templateRoute{
+		method:   method,
+		pattern:  pattern,
+		segments: make([]string, len(parts)),
+		params:   make([]string, len(parts)),
+		handler:  handler,
+	}
+	names := map[string]struct{}{}
+
+	for i, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("httpjson: empty segment in path template [path=%v]", pattern)
+		}
+
+		if part[0] != '{' {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("httpjson: invalid segment in path template [path=%v] [segment=%v]", pattern, part)
+			}
+
+			route.segments[i] = part
+			continue
+		}
+
+		name := strings.TrimSuffix(part[1:], "}")
+
+		if len(name) != len(part)-2 || name == "" || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("httpjson: invalid parameter in path template [path=%v] [segment=%v]", pattern, part)
+		}
+
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("httpjson: duplicated parameter in path template [path=%v] [param=%v]", pattern, name)
+		}
+
+		names[name] = struct{}{}
+		route.params[i] = name
+	}
+
+	return route, nil
+}
+
+// shape returns the pattern with all parameter names removed.
+// Two templates with the same shape match exactly the same paths.
+func (tr *templateRoute) shape() string {
+	parts := make([]string, len(tr.segments))
+
+	for i, seg := range tr.segments {
+		if tr.params[i] != "" {
+			parts[i] = "{}"
+		} else {
+			parts[i] = seg
+		}
+	}
+
+	return "/" + strings.Join(parts, "/")
+}
+
+// isLiteral reports whether tr doesn't have any parameter.
+func (tr *templateRoute) isLiteral() bool {
+	for _, param := range tr.params {
+		if param != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match matches path segments and returns parameter values.
+func (tr *templateRoute) match(segments []string) (params map[string]string, ok bool) {
+	if len(segments) != len(tr.segments) {
+		return
+	}
+
+	for i, seg := range segments {
+		if name := tr.params[i]; name != "" {
+			if seg == "" {
+				return nil, false
+			}
+
+			if params == nil {
+				params = map[string]string{}
+			}
+
+			params[name] = seg
+			continue
+		}
+
+		if seg != tr.segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// Add adds route to routes.
+// Routes are sorted so that literal segments take precedence over parameters.
+func (routes *templateRoutes) Add(route *templateRoute) error {
+	shape := route.shape()
+
+	for _, r := range *routes {
+		if r.method == route.method && r.shape() == shape {
+			return fmt.Errorf("httpjson: duplicated route [path=%v %v] [handlers=%v, %v]",
+				route.method, route.pattern, fullFuncName(r.handler.handler), fullFuncName(route.handler.handler))
+		}
+	}
+
+	*routes = append(*routes, route)
+	sort.SliceStable(*routes, func(i, j int) bool {
+		return (*routes)[i].less((*routes)[j])
+	})
+	return nil
+}
+
+func (tr *templateRoute) less(other *templateRoute) bool {
+	for i := 0; i < len(tr.segments) && i < len(other.segments); i++ {
+		isParam := tr.params[i] != ""
+		isOtherParam := other.params[i] != ""
+
+		if isParam != isOtherParam {
+			return !isParam
+		}
+	}
+
+	return false
+}
+
+// Lookup finds a route matching method and path.
+// HEAD matches routes with GET method.
+// If path matches some routes but method doesn't match, allowed contains methods of all matched routes.
+func (routes templateRoutes) Lookup(method, path string) (route *templateRoute, params map[string]string, allowed []string) {
+	if len(routes) == 0 {
+		return
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for _, r := range routes {
+		ps, ok := r.match(segments)
+
+		if !ok {
+			continue
+		}
+
+		if r.method == method || (method == http.MethodHead && r.method == http.MethodGet) {
+			return r, ps, nil
+		}
+
+		allowed = append(allowed, r.method)
+	}
+
+	return
+}
+
+// withPathParams returns a copy of r carrying path parameters.
+func withPathParams(r *http.Request, params map[string]string) *http.Request {
+	if len(params) == 0 {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params))
+}
+
+func pathParamsFrom(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(pathParamsKey{}).(map[string]string)
+	return params
+}
@@ -0,0 +1,108 @@
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testTemplateUserRequest struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type testTemplateUserResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func testTemplateGetUser(ctx context.Context, req *testTemplateUserRequest) (resp *testTemplateUserResponse, err error) {
+	resp = &testTemplateUserResponse{
+		ID:   req.ID,
+		Name: "user",
+	}
+	return
+}
+
+func testTemplateGetMe(ctx context.Context, req *testTemplateUserRequest) (resp *testTemplateUserResponse, err error) {
+	resp = &testTemplateUserResponse{
+		Name: "me",
+	}
+	return
+}
+
+func testTemplateUpdateUser(ctx context.Context, req *testTemplateUserRequest) (resp *testTemplateUserResponse, err error) {
+	resp = &testTemplateUserResponse{
+		ID:   req.ID,
+		Name: req.Name,
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testTemplateGetUser, rpc.HTTP("GET", "/users/{id}"))
+	rpc.Export(testTemplateGetMe, rpc.HTTP("GET", "/users/me"))
+	rpc.Export(testTemplateUpdateUser, rpc.HTTP("put", "/users/{id}"), rpc.HTTPStatus(http.StatusCreated))
+}
+
+func TestTemplateRoute(t *testing.T) {
+	a := assert.New(t)
+	server := newTestServer()
+	defer server.Close()
+
+	do := func(method, path, body string) (status int, header http.Header, resp *testTemplateUserResponse) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		a.NilError(err)
+		r, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+		defer r.Body.Close()
+
+		result := &struct {
+			Data *testTemplateUserResponse `json:"data"`
+		}{}
+		json.NewDecoder(r.Body).Decode(result)
+		return r.StatusCode, r.Header, result.Data
+	}
+
+	status, _, resp := do(http.MethodGet, "/users/42", "")
+	a.Equal(status, http.StatusOK)
+	a.Equal(resp, &testTemplateUserResponse{ID: 42, Name: "user"})
+
+	status, _, resp = do(http.MethodGet, "/users/me", "")
+	a.Equal(status, http.StatusOK)
+	a.Equal(resp.Name, "me")
+
+	status, _, resp = do(http.MethodPut, "/users/7", `{"id":1,"name":"shana"}`)
+	a.Equal(status, http.StatusCreated)
+	a.Equal(resp, &testTemplateUserResponse{ID: 7, Name: "shana"})
+
+	status, header, _ := do(http.MethodDelete, "/users/7", "")
+	a.Equal(status, http.StatusMethodNotAllowed)
+	a.Equal(header.Get("Allow"), "GET, HEAD, PUT, OPTIONS")
+}
+
+func TestParseTemplate(t *testing.T) {
+	a := assert.New(t)
+
+	for _, pattern := range []string{"", "users", "/users//x", "/users/{}", "/users/{id", "/users/x{id}", "/{id}/{id}"} {
+		_, err := parseTemplate(http.MethodGet, pattern, nil)
+		a.Use(&pattern)
+		a.NonNilError(err)
+	}
+
+	tr, err := parseTemplate(http.MethodGet, "/users/{id}/posts/{post}", nil)
+	a.NilError(err)
+	a.Equal(tr.shape(), "/users/{}/posts/{}")
+
+	params, ok := tr.match(strings.Split("users/1/posts/2", "/"))
+	a.Assert(ok)
+	a.Equal(params, map[string]string{"id": "1", "post": "2"})
+
+	_, ok = tr.match(strings.Split("users/1/comments/2", "/"))
+	a.Assert(!ok)
+}
@@ -0,0 +1,253 @@
+package httpjson
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-shana/core/errors"
+)
+
+const defaultTLSReloadInterval = 10 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig is the TLS config of http server.
+// TLS is enabled if CertFile is set.
+type TLSConfig struct {
+	CertFile       string        `shana:"cert_file"`       // The PEM encoded certificate file.
+	KeyFile        string        `shana:"key_file"`        // The PEM encoded private key file.
+	ClientCAFile   string        `shana:"client_ca_file"`  // The PEM encoded CA file to verify client certificates. If it's set, mutual TLS is required.
+	MinVersion     string        `shana:"min_version"`     // The minimum TLS version, one of "1.0", "1.1", "1.2" and "1.3". Default is "1.2".
+	CipherSuites   []string      `shana:"cipher_suites"`   // Names of allowed cipher suites for TLS 1.2 and below. Default is Go's secure cipher suites.
+	ReloadInterval time.Duration `shana:"reload_interval"` // How often to check whether files are changed on disk. Default is 10s.
+}
+
+// Enabled returns true if TLS is enabled.
+func (c *TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Validate validates the config.
+func (c *TLSConfig) Validate(ctx context.Context) {
+	if !c.Enabled() {
+		if c.KeyFile != "" || c.ClientCAFile != "" {
+			errors.Throwf("httpjson: cert_file is required in tls config")
+		}
+
+		return
+	}
+
+	if c.KeyFile == "" {
+		errors.Throwf("httpjson: key_file is required in tls config [cert_file=%v]", c.CertFile)
+		return
+	}
+
+	if c.MinVersion != "" {
+		if _, ok := tlsVersions[c.MinVersion]; !ok {
+			errors.Throwf("httpjson: invalid min_version in tls config [min_version=%v]", c.MinVersion)
+			return
+		}
+	}
+
+	errors.Check1(parseCipherSuites(c.CipherSuites))
+
+	if c.ReloadInterval < 0 {
+		errors.Throwf("httpjson: invalid reload_interval in tls config [reload_interval=%v]", c.ReloadInterval)
+		return
+	}
+
+	// Make sure all files are valid before serving.
+	errors.Check1(loadTLSFiles(c))
+}
+
+// Init initializes the config and fills zero values with defaults.
+func (c *TLSConfig) Init(ctx context.Context) {
+	if c.MinVersion == "" {
+		c.MinVersion = "1.2"
+	}
+
+	if c.ReloadInterval == 0 {
+		c.ReloadInterval = defaultTLSReloadInterval
+	}
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	suites := map[string]uint16{}
+
+	for _, cs := range tls.CipherSuites() {
+		suites[cs.Name] = cs.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+
+	for _, name := range names {
+		id, ok := suites[name]
+
+		if !ok {
+			return nil, fmt.Errorf("httpjson: invalid or insecure cipher suite in tls config [cipher_suite=%v]", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// tlsFiles contains loaded TLS certificate and client CA.
+type tlsFiles struct {
+	cert      tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  []time.Time
+}
+
+func loadTLSFiles(c *TLSConfig) (files *tlsFiles, err error) {
+	defer errors.Handle(&err)
+
+	files = &tlsFiles{
+		modTimes: errors.Check1(tlsModTimes(c)),
+	}
+	files.cert = errors.Check1(tls.LoadX509KeyPair(c.CertFile, c.KeyFile))
+
+	if c.ClientCAFile != "" {
+		pem := errors.Check1(os.ReadFile(c.ClientCAFile))
+		files.clientCAs = x509.NewCertPool()
+
+		if !files.clientCAs.AppendCertsFromPEM(pem) {
+			errors.Throwf("httpjson: no valid certificate in client_ca_file [client_ca_file=%v]", c.ClientCAFile)
+		}
+	}
+
+	return
+}
+
+func tlsModTimes(c *TLSConfig) ([]time.Time, error) {
+	names := []string{c.CertFile, c.KeyFile}
+
+	if c.ClientCAFile != "" {
+		names = append(names, c.ClientCAFile)
+	}
+
+	modTimes := make([]time.Time, 0, len(names))
+
+	for _, name := range names {
+		info, err := os.Stat(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	return modTimes, nil
+}
+
+// tlsReloader reloads TLS files when they change on disk.
+// Files are checked lazily in TLS handshake at most once in every reload interval.
+type tlsReloader struct {
+	config *TLSConfig
+	base   *tls.Config
+
+	mu        sync.Mutex
+	tlsConfig *tls.Config
+	modTimes  []time.Time
+	checkedAt time.Time
+}
+
+func newTLSReloader(c *TLSConfig) (reloader *tlsReloader, err error) {
+	defer errors.Handle(&err)
+
+	base := &tls.Config{
+		MinVersion:   tlsVersions[c.MinVersion],
+		CipherSuites: errors.Check1(parseCipherSuites(c.CipherSuites)),
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	reloader = &tlsReloader{
+		config: c,
+		base:   base,
+	}
+	reloader.reload(errors.Check1(loadTLSFiles(c)))
+	return
+}
+
+func (r *tlsReloader) reload(files *tlsFiles) {
+	config := r.base.Clone()
+	config.Certificates = []tls.Certificate{files.cert}
+
+	if files.clientCAs != nil {
+		config.ClientCAs = files.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.tlsConfig = config
+	r.modTimes = files.modTimes
+}
+
+// TLSConfig returns the tls.Config used by http.Server.
+func (r *tlsReloader) TLSConfig() *tls.Config {
+	config := r.base.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return r.current(), nil
+	}
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return &r.current().Certificates[0], nil
+	}
+	return config
+}
+
+func (r *tlsReloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(r.checkedAt) < r.config.ReloadInterval {
+		return r.tlsConfig
+	}
+
+	r.checkedAt = now
+	modTimes, err := tlsModTimes(r.config)
+
+	if err != nil || !isModTimesChanged(r.modTimes, modTimes) {
+		return r.tlsConfig
+	}
+
+	files, err := loadTLSFiles(r.config)
+
+	if err != nil {
+		// TODO: use logger instead of fmt.
+		fmt.Fprintf(os.Stderr, "httpjson: fail to reload TLS files and keep using old ones [err=%v]\n", err)
+		return r.tlsConfig
+	}
+
+	r.reload(files)
+	return r.tlsConfig
+}
+
+func isModTimesChanged(old, modTimes []time.Time) bool {
+	if len(old) != len(modTimes) {
+		return true
+	}
+
+	for i, t := range old {
+		if !t.Equal(modTimes[i]) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package httpjson
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-shana/core/errors"
+	"github.com/huandu/go-assert"
+)
+
+func writeTestCert(t *testing.T, dir, name string, modTime time.Time) (certFile, keyFile string) {
+	a := assert.New(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	a.NilError(err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	a.NilError(err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	a.NilError(err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	a.NilError(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	a.NilError(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	a.NilError(os.Chtimes(certFile, modTime, modTime))
+	a.NilError(os.Chtimes(keyFile, modTime, modTime))
+	return
+}
+
+func TestTLSConfigValidate(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "first", time.Now())
+	ctx := context.Background()
+	validate := func(c *TLSConfig) (err error) {
+		defer errors.Handle(&err)
+		c.Validate(ctx)
+		return
+	}
+
+	a.NilError(validate(&TLSConfig{}))
+	a.NilError(validate(&TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"}))
+	a.NonNilError(validate(&TLSConfig{KeyFile: keyFile}))
+	a.NonNilError(validate(&TLSConfig{CertFile: certFile}))
+	a.NonNilError(validate(&TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "2.0"}))
+	a.NonNilError(validate(&TLSConfig{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}))
+	a.NonNilError(validate(&TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: filepath.Join(dir, "not-exist.pem")}))
+}
+
+func TestTLSReloader(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "first", time.Now().Add(-time.Minute))
+	config := &TLSConfig{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+	config.Init(context.Background())
+	config.ReloadInterval = time.Nanosecond
+
+	reloader, err := newTLSReloader(config)
+	a.NilError(err)
+	getCommonName := func() string {
+		cert, err := reloader.TLSConfig().GetCertificate(nil)
+		a.NilError(err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		a.NilError(err)
+		return leaf.Subject.CommonName
+	}
+	a.Equal(getCommonName(), "first")
+
+	writeTestCert(t, dir, "second", time.Now())
+	a.Equal(getCommonName(), "second")
+}
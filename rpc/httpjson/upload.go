@@ -0,0 +1,188 @@
+package httpjson
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+
+	"github.com/go-shana/core/data"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+const (
+	contentTypeMultipart = "multipart/form-data"
+
+	defaultUploadMaxSize   = 32 << 20
+	defaultUploadMaxMemory = 8 << 20
+)
+
+var (
+	errRequestTooLarge = newStatusError(http.StatusRequestEntityTooLarge, "httpjson: request body is too large")
+	errFileTooLarge    = newStatusError(http.StatusRequestEntityTooLarge, "httpjson: uploaded file is too large")
+)
+
+var (
+	typeOfFile  = reflect.TypeOf((*rpc.File)(nil))
+	typeOfFiles = reflect.TypeOf([]*rpc.File(nil))
+)
+
+// UploadConfig is the config of multipart/form-data requests.
+//
+// Files in request are bound to request fields in type *rpc.File or []*rpc.File.
+// Uploads are not streamed. The whole body is buffered before handler is called.
+// Files larger than MaxMemory are buffered in temporary files which are removed after handler returns.
+type UploadConfig struct {
+	MaxSize     int64 `shana:"max_size"`      // Max size of a multipart body in bytes. Default is 32MiB.
+	MaxMemory   int64 `shana:"max_memory"`    // Max size of files kept in memory in bytes. Default is 8MiB.
+	MaxFileSize int64 `shana:"max_file_size"` // Max size of an uploaded file in bytes. It's checked after the body is buffered. No limit other than MaxSize by default.
+}
+
+// Validate validates the config.
+func (c *UploadConfig) Validate(ctx context.Context) {
+	if c.MaxSize < 0 {
+		errors.Throwf("httpjson: invalid upload max size [max_size=%v]", c.MaxSize)
+		return
+	}
+
+	if c.MaxMemory < 0 {
+		errors.Throwf("httpjson: invalid upload max memory [max_memory=%v]", c.MaxMemory)
+		return
+	}
+
+	if c.MaxFileSize < 0 {
+		errors.Throwf("httpjson: invalid upload max file size [max_file_size=%v]", c.MaxFileSize)
+		return
+	}
+}
+
+func (c *UploadConfig) maxSize() int64 {
+	if c.MaxSize == 0 {
+		return defaultUploadMaxSize
+	}
+
+	return c.MaxSize
+}
+
+func (c *UploadConfig) maxMemory() int64 {
+	if c.MaxMemory == 0 {
+		return defaultUploadMaxMemory
+	}
+
+	return c.MaxMemory
+}
+
+// decodeMultipart decodes values and files in multipart body of r to the request pointed by ptr.
+func decodeMultipart(ptr reflect.Value, r *http.Request, config *UploadConfig) (err error) {
+	defer errors.Handle(&err)
+
+	r.Body = http.MaxBytesReader(nil, r.Body, config.maxSize())
+
+	errors.Check(checkBodySize(r.ParseMultipartForm(config.maxMemory())))
+
+	form := r.MultipartForm
+	errors.Check(unmarshalValues(ptr, form.Value))
+	errors.Check(bindFiles(ptr, form.File, config.MaxFileSize))
+	return
+}
+
+// removeUploadedFiles removes temporary files of uploaded files in r.
+// The http.Server only removes them for its own request, while r may be a copy of it.
+func removeUploadedFiles(r *http.Request) {
+	if r.MultipartForm != nil {
+		r.MultipartForm.RemoveAll()
+	}
+}
+
+// checkBodySize returns errRequestTooLarge if err is caused by reading a body over the limit of http.MaxBytesReader.
+func checkBodySize(err error) error {
+	var tooLarge *http.MaxBytesError
+
+	if errors.As(err, &tooLarge) {
+		return errors.Join(errRequestTooLarge, err)
+	}
+
+	return err
+}
+
+// bindFiles sets files to fields in type *rpc.File or []*rpc.File.
+// Fields are matched by names in the same way as unmarshalValues.
+func bindFiles(ptr reflect.Value, files map[string][]*multipart.FileHeader, maxFileSize int64) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	val := ptr.Elem()
+	t := val.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() || (field.Type != typeOfFile && field.Type != typeOfFiles) {
+			continue
+		}
+
+		ft := data.ParseFieldTag(field.Tag.Get("json"))
+
+		if ft.Skipped {
+			continue
+		}
+
+		name := field.Name
+
+		if ft.Alias != "" {
+			name = ft.Alias
+		}
+
+		headers := files[name]
+
+		if len(headers) == 0 {
+			continue
+		}
+
+		uploaded := make([]*rpc.File, 0, len(headers))
+
+		for _, fh := range headers {
+			if maxFileSize > 0 && fh.Size > maxFileSize {
+				return errFileTooLarge
+			}
+
+			uploaded = append(uploaded, newFile(fh))
+		}
+
+		if field.Type == typeOfFile {
+			val.Field(i).Set(reflect.ValueOf(uploaded[0]))
+		} else {
+			val.Field(i).Set(reflect.ValueOf(uploaded))
+		}
+	}
+
+	return nil
+}
+
+func newFile(fh *multipart.FileHeader) *rpc.File {
+	return rpc.NewFile(fh.Filename, fh.Header, fh.Size, func() (io.ReadCloser, error) {
+		return fh.Open()
+	})
+}
+
+// hasFileFields reports whether t is a struct with any field in type *rpc.File or []*rpc.File.
+func hasFileFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if ft := t.Field(i).Type; ft == typeOfFile || ft == typeOfFiles {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testUploadRequest struct {
+	Title  string      `json:"title"`
+	Count  int         `json:"count"`
+	Avatar *rpc.File   `json:"avatar"`
+	Photos []*rpc.File `json:"photos"`
+	Ignore *rpc.File   `json:"-"`
+}
+
+type testUploadResponse struct {
+	Title       string `json:"title"`
+	Count       int    `json:"count"`
+	Avatar      string `json:"avatar"`
+	AvatarName  string `json:"avatarName"`
+	AvatarType  string `json:"avatarType"`
+	Photos      int    `json:"photos"`
+	HasIgnored  bool   `json:"hasIgnored"`
+	PhotoLength int64  `json:"photoLength"`
+}
+
+func testUpload(ctx context.Context, req *testUploadRequest) (resp *testUploadResponse, err error) {
+	resp = &testUploadResponse{
+		Title:      req.Title,
+		Count:      req.Count,
+		Photos:     len(req.Photos),
+		HasIgnored: req.Ignore != nil,
+	}
+
+	if req.Avatar != nil {
+		f, e := req.Avatar.Open()
+
+		if e != nil {
+			err = e
+			return
+		}
+
+		defer f.Close()
+		content, e := io.ReadAll(f)
+
+		if e != nil {
+			err = e
+			return
+		}
+
+		resp.Avatar = string(content)
+		resp.AvatarName = req.Avatar.Filename
+		resp.AvatarType = req.Avatar.ContentType()
+	}
+
+	for _, photo := range req.Photos {
+		resp.PhotoLength += photo.Size
+	}
+
+	return
+}
+
+func init() {
+	rpc.Export(testUpload)
+}
+
+type testUploadPart struct {
+	field, filename, content string
+}
+
+func newTestMultipart(a *assert.A, parts ...testUploadPart) (body *bytes.Buffer, contentType string) {
+	body = &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	for _, part := range parts {
+		if part.filename == "" {
+			a.NilError(w.WriteField(part.field, part.content))
+			continue
+		}
+
+		fw, err := w.CreateFormFile(part.field, part.filename)
+		a.NilError(err)
+		_, err = fw.Write([]byte(part.content))
+		a.NilError(err)
+	}
+
+	a.NilError(w.Close())
+	return body, w.FormDataContentType()
+}
+
+func TestUpload(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Upload: UploadConfig{
+			MaxSize:     4096,
+			MaxMemory:   16, // Store large files in temporary files.
+			MaxFileSize: 1024,
+		},
+	}))
+	defer server.Close()
+
+	post := func(body io.Reader, contentType string) (int, map[string]any) {
+		resp, err := http.Post(server.URL+"/test-upload", contentType, body)
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		var result map[string]any
+		a.NilError(json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	body, contentType := newTestMultipart(a,
+		testUploadPart{"title", "", "Shana"},
+		testUploadPart{"count", "", "3"},
+		testUploadPart{"avatar", "avatar.txt", strings.Repeat("a", 100)},
+		testUploadPart{"photos", "1.jpg", "123"},
+		testUploadPart{"photos", "2.jpg", "4567"},
+		testUploadPart{"Ignore", "ignore.txt", "ignored"},
+	)
+	status, result := post(body, contentType)
+	a.Equal(status, http.StatusOK)
+	a.Equal(result["data"], map[string]any{
+		"title":       "Shana",
+		"count":       float64(3),
+		"avatar":      strings.Repeat("a", 100),
+		"avatarName":  "avatar.txt",
+		"avatarType":  "application/octet-stream",
+		"photos":      float64(2),
+		"hasIgnored":  false,
+		"photoLength": float64(7),
+	})
+
+	// Form body still works.
+	status, result = post(strings.NewReader("title=Shana&count=1"), contentTypeForm)
+	a.Equal(status, http.StatusOK)
+	a.Equal(result["data"].(map[string]any)["title"], "Shana")
+
+	body, contentType = newTestMultipart(a, testUploadPart{"avatar", "avatar.txt", strings.Repeat("a", 2048)})
+	status, result = post(body, contentType)
+	a.Equal(status, http.StatusRequestEntityTooLarge)
+	a.Equal(result["error"], errFileTooLarge.Error())
+
+	body, contentType = newTestMultipart(a, testUploadPart{"title", "", strings.Repeat("a", 8192)})
+	status, result = post(body, contentType)
+	a.Equal(status, http.StatusRequestEntityTooLarge)
+	a.Equal(result["error"], errRequestTooLarge.Error())
+}
+
+func TestUploadTempFiles(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	router := NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		Upload: UploadConfig{
+			MaxMemory: 16, // Store large files in temporary files.
+		},
+	})
+
+	body, contentType := newTestMultipart(a, testUploadPart{"avatar", "avatar.txt", strings.Repeat("a", 1024)})
+	req := httptest.NewRequest(http.MethodPost, "/test-upload", body)
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	a.Equal(rec.Code, http.StatusOK)
+
+	// Temporary files are removed by router as there is no http.Server.
+	entries, err := os.ReadDir(dir)
+	a.NilError(err)
+	a.Equal(len(entries), 0)
+}
+
+func TestOpenAPIUpload(t *testing.T) {
+	a := assert.New(t)
+	doc := &openAPIDocument{
+		types:   map[reflect.Type]string{},
+		names:   map[string]reflect.Type{},
+		schemas: map[string]*openAPISchema{},
+	}
+
+	a.Assert(hasFileFields(reflect.TypeOf(&testUploadRequest{})))
+	a.Assert(!hasFileFields(reflect.TypeOf(&testClientRequest{})))
+	a.Equal(doc.schema(typeOfFile), &openAPISchema{Type: "string", Format: "binary"})
+	a.Equal(doc.schema(typeOfFiles), &openAPISchema{Type: "array", Items: &openAPISchema{Type: "string", Format: "binary"}})
+}
@@ -0,0 +1,377 @@
+package httpjson
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-shana/core/data"
+	"github.com/go-shana/core/errors"
+)
+
+// maxValuesIndex is the max index of a slice in values keys, e.g. "items.1000.name".
+const maxValuesIndex = 1000
+
+func unmarshalQueryString(ptr reflect.Value, u *url.URL) error {
+	return unmarshalValues(ptr, u.Query())
+}
+
+// unmarshalValues decodes values, e.g. query string, form or path params, to the struct pointed by ptr.
+//
+// A key is a path to a field in the query syntax of data.Data, e.g. "filter.status" or "items.0.name".
+// Brackets are also supported, e.g. "filter[status]", "items[0][name]" and "ids[]".
+//
+// Values are converted to the types of target fields.
+// All values of a repeated key are decoded to a slice field.
+// Other fields use the last value.
+// A bool field accepts an empty value as true, e.g. "?verbose".
+func unmarshalValues(ptr reflect.Value, values url.Values) error {
+	val := ptr.Elem()
+
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("httpjson: request must be a struct [type=%v]", val.Type())
+	}
+
+	keys := make([]string, 0, len(values))
+
+	for k, vs := range values {
+		if len(vs) != 0 {
+			keys = append(keys, k)
+		}
+	}
+
+	// Decode keys in order so that the result and error are stable.
+	sort.Strings(keys)
+	raw := data.RawData{}
+	t := val.Type()
+
+	for _, k := range keys {
+		path := parseValuesKey(k)
+
+		if len(path) == 0 {
+			continue
+		}
+
+		node, err := setValues(map[string]any(raw), t, path, values[k])
+
+		if err != nil {
+			return fmt.Errorf("httpjson: invalid value of field %v [value=%v] [err=%w]", k, strings.Join(values[k], ","), err)
+		}
+
+		raw = data.RawData(node.(map[string]any))
+	}
+
+	if err := decodeRawData(raw, ptr); err != nil {
+		// Decoder doesn't report which field fails. Find it by decoding keys one by one.
+		for _, k := range keys {
+			path := parseValuesKey(k)
+
+			if len(path) == 0 {
+				continue
+			}
+
+			node, e := setValues(map[string]any{}, t, path, values[k])
+
+			if e != nil {
+				continue
+			}
+
+			if e = decodeRawData(data.RawData(node.(map[string]any)), reflect.New(t)); e != nil {
+				return fmt.Errorf("httpjson: invalid value of field %v [value=%v] [err=%w]", k, strings.Join(values[k], ","), e)
+			}
+		}
+
+		return fmt.Errorf("httpjson: invalid values [err=%w]", err)
+	}
+
+	return nil
+}
+
+func decodeRawData(raw data.RawData, ptr reflect.Value) error {
+	d := data.Make(raw)
+	dec := &data.Decoder{
+		TagName: "json",
+	}
+	return dec.Decode(d, ptr.Interface())
+}
+
+// parseValuesKey splits key to a path, e.g. "a.b[0][c]" to ["a", "b", "0", "c"].
+// An empty bracket, e.g. "ids[]", is an empty element in path.
+func parseValuesKey(key string) (path []string) {
+	buf := &strings.Builder{}
+	pending := false
+
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; c {
+		case '\\':
+			if i+1 < len(key) {
+				i++
+			}
+
+			buf.WriteByte(key[i])
+			pending = true
+
+		case '.':
+			path = append(path, buf.String())
+			buf.Reset()
+			pending = true
+
+		case '[':
+			end := strings.IndexByte(key[i+1:], ']')
+
+			if end < 0 {
+				buf.WriteByte(c)
+				pending = true
+				continue
+			}
+
+			if pending {
+				path = append(path, buf.String())
+				buf.Reset()
+			}
+
+			path = append(path, key[i+1:i+1+end])
+			i += end + 1
+			pending = false
+
+			// Allow "a[b].c".
+			if i+1 < len(key) && key[i+1] == '.' {
+				i++
+				pending = true
+			}
+
+		default:
+			buf.WriteByte(c)
+			pending = true
+		}
+	}
+
+	if pending {
+		path = append(path, buf.String())
+	}
+
+	return
+}
+
+var (
+	errNotContainer = errors.New("value cannot have fields")
+	errIndex        = errors.New("invalid index")
+)
+
+// setValues sets vs to node at path according to the type t of node.
+// It returns the updated node.
+func setValues(node any, t reflect.Type, path []string, vs []string) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if len(path) == 0 {
+		return convertValues(t, vs)
+	}
+
+	seg := path[0]
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == typeOfTime {
+			return nil, errNotContainer
+		}
+
+		field, ok := findValuesField(t, seg)
+
+		if !ok {
+			return node, nil
+		}
+
+		return setMapValues(node, field.Type, seg, path[1:], vs)
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, errNotContainer
+		}
+
+		return setMapValues(node, t.Elem(), seg, path[1:], vs)
+
+	case reflect.Interface:
+		return setMapValues(node, t, seg, path[1:], vs)
+
+	case reflect.Slice, reflect.Array:
+		// Empty bracket is for all values, e.g. "ids[]".
+		if seg == "" && len(path) == 1 {
+			return convertValues(t, vs)
+		}
+
+		idx, err := strconv.Atoi(seg)
+
+		if err != nil || idx < 0 || idx > maxValuesIndex || (t.Kind() == reflect.Array && idx >= t.Len()) {
+			return nil, errIndex
+		}
+
+		list, _ := node.([]any)
+
+		for len(list) <= idx {
+			list = append(list, nil)
+		}
+
+		elem, err := setValues(list[idx], t.Elem(), path[1:], vs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list[idx] = elem
+		return list, nil
+	}
+
+	return nil, errNotContainer
+}
+
+func setMapValues(node any, t reflect.Type, key string, path []string, vs []string) (any, error) {
+	m, _ := node.(map[string]any)
+
+	if m == nil {
+		m = map[string]any{}
+	}
+
+	v, err := setValues(m[key], t, path, vs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	m[key] = v
+	return m, nil
+}
+
+// findValuesField finds the field named name in the same way as data.Decoder.
+func findValuesField(t reflect.Type, name string) (field reflect.StructField, ok bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if !f.IsExported() {
+			continue
+		}
+
+		ft := data.ParseFieldTag(f.Tag.Get("json"))
+
+		if ft.Skipped {
+			continue
+		}
+
+		if ft.Squash {
+			ftype := f.Type
+
+			for ftype.Kind() == reflect.Pointer {
+				ftype = ftype.Elem()
+			}
+
+			if ftype.Kind() == reflect.Struct {
+				if field, ok = findValuesField(ftype, name); ok {
+					return
+				}
+
+				continue
+			}
+		}
+
+		alias := f.Name
+
+		if ft.Alias != "" {
+			alias = ft.Alias
+		}
+
+		if alias == name {
+			return f, true
+		}
+	}
+
+	return
+}
+
+// convertValues converts vs to the value of type t.
+func convertValues(t reflect.Type, vs []string) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t != typeOfBytes && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		if t.Kind() == reflect.Array && len(vs) > t.Len() {
+			return nil, errIndex
+		}
+
+		list := make([]any, 0, len(vs))
+
+		for _, v := range vs {
+			elem, err := convertValue(t.Elem(), v)
+
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, elem)
+		}
+
+		return list, nil
+	}
+
+	return convertValue(t, vs[len(vs)-1])
+}
+
+// convertValue converts v to the value of type t.
+func convertValue(t reflect.Type, v string) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case typeOfTime:
+		return time.Parse(time.RFC3339Nano, v)
+	case typeOfDuration:
+		return v, nil
+	case typeOfBytes:
+		return []byte(v), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Interface:
+		return v, nil
+
+	case reflect.Bool:
+		switch strings.ToLower(v) {
+		case "", "on":
+			return true, nil
+		case "off":
+			return false, nil
+		}
+
+		return unwrapNumError(strconv.ParseBool(v))
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return unwrapNumError(strconv.ParseInt(v, 10, t.Bits()))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return unwrapNumError(strconv.ParseUint(v, 10, t.Bits()))
+
+	case reflect.Float32, reflect.Float64:
+		return unwrapNumError(strconv.ParseFloat(v, t.Bits()))
+	}
+
+	return nil, fmt.Errorf("cannot convert a value to type %v", t)
+}
+
+// unwrapNumError removes redundant function name and value in strconv errors.
+func unwrapNumError[T any](v T, err error) (any, error) {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return nil, ne.Err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,141 @@
+package httpjson
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huandu/go-assert"
+)
+
+func TestParseValuesKey(t *testing.T) {
+	a := assert.New(t)
+
+	cases := []struct {
+		key  string
+		path []string
+	}{
+		{"name", []string{"name"}},
+		{"filter.status", []string{"filter", "status"}},
+		{"filter[status]", []string{"filter", "status"}},
+		{"items[0][name]", []string{"items", "0", "name"}},
+		{"items[0].name", []string{"items", "0", "name"}},
+		{"items.0.name", []string{"items", "0", "name"}},
+		{"ids[]", []string{"ids", ""}},
+		{`a\.b.c`, []string{"a.b", "c"}},
+		{"a[b", []string{"a[b"}},
+	}
+
+	for _, c := range cases {
+		a.Use(&c)
+		a.Equal(parseValuesKey(c.key), c.path)
+	}
+}
+
+type testValuesFilter struct {
+	Status string `json:"status"`
+	Closed *bool  `json:"closed"`
+}
+
+type testValuesItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type testValuesRequest struct {
+	Name     string            `json:"name"`
+	Code     string            `json:"code"`
+	Count    int               `json:"count"`
+	Verbose  bool              `json:"verbose"`
+	IDs      []int64           `json:"ids"`
+	Tags     []string          `json:"tags"`
+	Filter   testValuesFilter  `json:"filter"`
+	Items    []*testValuesItem `json:"items"`
+	Labels   map[string]int    `json:"labels"`
+	Since    time.Time         `json:"since"`
+	Timeout  time.Duration     `json:"timeout"`
+	Data     []byte            `json:"data"`
+	Ignored  string            `json:"-"`
+	Untagged uint8
+}
+
+func TestUnmarshalValues(t *testing.T) {
+	a := assert.New(t)
+	values, err := url.ParseQuery(strings.Join([]string{
+		"name=a",
+		"name=Shana",
+		"code=007",
+		"count=3",
+		"verbose",
+		"ids=1",
+		"ids=2",
+		"tags[]=x",
+		"tags[]=y",
+		"filter.status=open",
+		"filter[closed]=false",
+		"items[1][name]=book",
+		"items.1.price=9.5",
+		"labels[a]=1",
+		"labels.b=2",
+		"since=2023-01-02T03:04:05Z",
+		"timeout=3s",
+		"data=abc",
+		"Ignored=x",
+		"Untagged=8",
+		"unknown.field=1",
+	}, "&"))
+	a.NilError(err)
+
+	req := &testValuesRequest{}
+	a.NilError(unmarshalValues(reflect.ValueOf(req), values))
+
+	closed := false
+	a.Equal(req, &testValuesRequest{
+		Name:    "Shana",
+		Code:    "007",
+		Count:   3,
+		Verbose: true,
+		IDs:     []int64{1, 2},
+		Tags:    []string{"x", "y"},
+		Filter: testValuesFilter{
+			Status: "open",
+			Closed: &closed,
+		},
+		Items:    []*testValuesItem{nil, {Name: "book", Price: 9.5}},
+		Labels:   map[string]int{"a": 1, "b": 2},
+		Since:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Timeout:  3 * time.Second,
+		Data:     []byte("abc"),
+		Untagged: 8,
+	})
+}
+
+func TestUnmarshalValuesErrors(t *testing.T) {
+	a := assert.New(t)
+
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{"count=abc", "httpjson: invalid value of field count [value=abc] [err=invalid syntax]"},
+		{"ids=1&ids=x", "httpjson: invalid value of field ids [value=1,x] [err=invalid syntax]"},
+		{"verbose=maybe", "httpjson: invalid value of field verbose [value=maybe] [err=invalid syntax]"},
+		{"Untagged=256", "httpjson: invalid value of field Untagged [value=256] [err=value out of range]"},
+		{"name.first=a", "httpjson: invalid value of field name.first [value=a] [err=value cannot have fields]"},
+		{"items[x][name]=a", "httpjson: invalid value of field items[x][name] [value=a] [err=invalid index]"},
+		{"timeout=abc", `httpjson: invalid value of field timeout [value=abc] [err=time: invalid duration "abc"]`},
+		{"items[1001][name]=a", "httpjson: invalid value of field items[1001][name] [value=a] [err=invalid index]"},
+	}
+
+	for _, c := range cases {
+		values, err := url.ParseQuery(c.query)
+		a.NilError(err)
+
+		err = unmarshalValues(reflect.ValueOf(&testValuesRequest{}), values)
+		a.Use(&c)
+		a.NonNilError(err)
+		a.Equal(err.Error(), c.err)
+	}
+}
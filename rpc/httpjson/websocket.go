@@ -0,0 +1,482 @@
+package httpjson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+const (
+	defaultWebSocketPath           = "/_shana/ws"
+	defaultWebSocketMaxMessageSize = 1 << 20
+	defaultWebSocketMaxInFlight    = 16
+	defaultWebSocketPingInterval   = 30 * time.Second
+	defaultWebSocketReadTimeout    = 60 * time.Second
+
+	wsWriteTimeout = 10 * time.Second
+)
+
+var (
+	errNotWebSocket        = errors.New("httpjson: not a websocket handshake request")
+	errOriginNotAllowed    = errors.New("httpjson: origin is not allowed")
+	errWebSocketClosed     = errors.New("httpjson: websocket is closed")
+	errInvalidWebSocketMsg = errors.New("httpjson: invalid websocket message")
+)
+
+// WebSocketConfig is the config of WebSocket endpoint.
+//
+// Clients call exported handlers by sending JSON text messages like:
+//
+//	{"id": 1, "path": "/foo/bar", "params": {"name": "shana"}}
+//
+// Calls are dispatched concurrently through the same route, authentication and rate limiting
+// as a normal POST request. At most MaxInFlight calls run concurrently in a connection
+// and reading is paused until any of them returns. Every call is replied with the same id and a Response:
+//
+//	{"id": 1, "response": {"data": {...}}}
+//
+// Server can push messages to clients with WebSocketConn.Push or Broadcast:
+//
+//	{"event": "news", "data": {...}}
+//
+// Server sends a ping every PingInterval. A connection is closed if nothing is read in ReadTimeout.
+// All connections are closed when server shuts down.
+type WebSocketConfig struct {
+	Enabled        bool          `shana:"enabled"`          // Enable WebSocket endpoint.
+	Path           string        `shana:"path"`             // The path of WebSocket endpoint. Default is "/_shana/ws".
+	MaxMessageSize int           `shana:"max_message_size"` // Max size of a message from client in bytes. Default is 1MiB.
+	MaxInFlight    int           `shana:"max_in_flight"`    // Max concurrent calls in a connection. Default is 16.
+	PingInterval   time.Duration `shana:"ping_interval"`    // How often to send a ping. Default is 30s.
+	ReadTimeout    time.Duration `shana:"read_timeout"`     // How long a connection can be silent. Default is 60s.
+}
+
+// Validate validates the config.
+func (c *WebSocketConfig) Validate(ctx context.Context) {
+	if c.Path != "" && c.Path[0] != '/' {
+		errors.Throwf("httpjson: websocket path must start with '/' [path=%v]", c.Path)
+		return
+	}
+
+	if c.MaxMessageSize < 0 {
+		errors.Throwf("httpjson: invalid websocket max message size [max_message_size=%v]", c.MaxMessageSize)
+		return
+	}
+
+	if c.MaxInFlight < 0 {
+		errors.Throwf("httpjson: invalid websocket max in-flight calls [max_in_flight=%v]", c.MaxInFlight)
+		return
+	}
+
+	if c.PingInterval < 0 {
+		errors.Throwf("httpjson: invalid websocket ping interval [ping_interval=%v]", c.PingInterval)
+		return
+	}
+
+	if c.ReadTimeout < 0 {
+		errors.Throwf("httpjson: invalid websocket read timeout [read_timeout=%v]", c.ReadTimeout)
+		return
+	}
+}
+
+func (c *WebSocketConfig) path() string {
+	if c.Path == "" {
+		return defaultWebSocketPath
+	}
+
+	return c.Path
+}
+
+func (c *WebSocketConfig) maxMessageSize() int64 {
+	if c.MaxMessageSize == 0 {
+		return defaultWebSocketMaxMessageSize
+	}
+
+	return int64(c.MaxMessageSize)
+}
+
+func (c *WebSocketConfig) maxInFlight() int {
+	if c.MaxInFlight == 0 {
+		return defaultWebSocketMaxInFlight
+	}
+
+	return c.MaxInFlight
+}
+
+func (c *WebSocketConfig) pingInterval() time.Duration {
+	if c.PingInterval == 0 {
+		return defaultWebSocketPingInterval
+	}
+
+	return c.PingInterval
+}
+
+func (c *WebSocketConfig) readTimeout() time.Duration {
+	if c.ReadTimeout == 0 {
+		return defaultWebSocketReadTimeout
+	}
+
+	return c.ReadTimeout
+}
+
+type wsCall struct {
+	ID     json.RawMessage `json:"id"`
+	Path   string          `json:"path"`
+	Params json.RawMessage `json:"params"`
+}
+
+type wsReply struct {
+	ID       json.RawMessage `json:"id"`
+	Response json.RawMessage `json:"response"`
+}
+
+type wsPush struct {
+	Event string `json:"event"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// WebSocketConn is a WebSocket connection.
+type WebSocketConn struct {
+	id          string
+	rw          *bufio.ReadWriter
+	conn        net.Conn
+	readTimeout time.Duration
+	mu          sync.Mutex // Guards writes to rw.
+	closed      atomic.Bool
+}
+
+var wsConns sync.Map // All connected *WebSocketConn.
+
+// wsConnSet is a set of WebSocket connections served by a router.
+// They are closed when server shuts down, as http.Server doesn't track hijacked connections.
+type wsConnSet struct {
+	mu     sync.Mutex
+	conns  map[*WebSocketConn]struct{}
+	closed bool
+}
+
+// Add adds conn to the set. It returns false if the set is closed.
+func (s *wsConnSet) Add(conn *WebSocketConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if s.conns == nil {
+		s.conns = map[*WebSocketConn]struct{}{}
+	}
+
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+// Remove removes conn from the set.
+func (s *wsConnSet) Remove(conn *WebSocketConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, conn)
+}
+
+// Close closes all connections in the set and refuses new ones.
+func (s *wsConnSet) Close() {
+	s.mu.Lock()
+	s.closed = true
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for conn := range conns {
+		conn.close(wsCloseGoingAway, "server is shutting down")
+	}
+}
+
+type websocketKey struct{}
+
+// WebSocketFrom returns the WebSocketConn in ctx.
+// It returns nil if the handler is not called through WebSocket.
+func WebSocketFrom(ctx context.Context) *WebSocketConn {
+	conn, _ := ctx.Value(websocketKey{}).(*WebSocketConn)
+	return conn
+}
+
+// Broadcast pushes a message with event and data to all connected WebSocket clients.
+func Broadcast(event string, data any) {
+	payload, err := sonic.Marshal(&wsPush{
+		Event: event,
+		Data:  data,
+	})
+
+	if err != nil {
+		return
+	}
+
+	wsConns.Range(func(key, value any) bool {
+		key.(*WebSocketConn).write(wsOpText, payload)
+		return true
+	})
+}
+
+// ID returns the unique ID of the connection.
+func (c *WebSocketConn) ID() string {
+	return c.id
+}
+
+// Push pushes a message with event and data to client.
+func (c *WebSocketConn) Push(event string, data any) error {
+	payload, err := sonic.Marshal(&wsPush{
+		Event: event,
+		Data:  data,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return c.write(wsOpText, payload)
+}
+
+// Close closes the connection normally.
+func (c *WebSocketConn) Close() error {
+	return c.close(wsCloseNormal, "")
+}
+
+func (c *WebSocketConn) write(opcode byte, payload []byte) error {
+	if c.closed.Load() {
+		return errWebSocketClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Don't block forever on a client which doesn't read.
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return writeFrame(c.rw.Writer, opcode, payload)
+}
+
+func (c *WebSocketConn) close(code int, reason string) error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	wsConns.Delete(c)
+
+	c.mu.Lock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	writeFrame(c.rw.Writer, wsOpClose, closePayload(code, reason))
+	c.mu.Unlock()
+
+	return c.conn.Close()
+}
+
+// readMessage reads a complete message, handling control frames in between.
+// Every frame, including pong, must be read in read timeout.
+func (c *WebSocketConn) readMessage(maxSize int64) (opcode byte, message []byte, err error) {
+	buf := &bytes.Buffer{}
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		fin, op, payload, e := readFrame(c.rw.Reader, maxSize)
+
+		if e != nil {
+			err = e
+			return
+		}
+
+		switch op {
+		case wsOpPing:
+			c.write(wsOpPong, payload)
+			continue
+
+		case wsOpPong:
+			continue
+
+		case wsOpClose:
+			err = &wsCloseError{code: wsCloseNormal}
+			return
+
+		case wsOpText, wsOpBinary:
+			if buf.Len() != 0 || opcode != 0 {
+				err = &wsCloseError{code: wsCloseProtocolError, reason: "unexpected data frame"}
+				return
+			}
+
+			opcode = op
+
+		case wsOpContinuation:
+			if opcode == 0 {
+				err = &wsCloseError{code: wsCloseProtocolError, reason: "unexpected continuation frame"}
+				return
+			}
+
+		default:
+			err = &wsCloseError{code: wsCloseProtocolError, reason: "unknown opcode"}
+			return
+		}
+
+		if int64(buf.Len()+len(payload)) > maxSize {
+			err = &wsCloseError{code: wsCloseTooLarge, reason: "message is too large"}
+			return
+		}
+
+		buf.Write(payload)
+
+		if fin {
+			message = buf.Bytes()
+			return
+		}
+	}
+}
+
+// serveWebSocket returns a http.HandlerFunc serving WebSocket connections.
+func (r *Router) serveWebSocket(config *Config) http.HandlerFunc {
+	maxSize := config.WebSocket.maxMessageSize()
+	maxInFlight := config.WebSocket.maxInFlight()
+	pingInterval := config.WebSocket.pingInterval()
+	readTimeout := config.WebSocket.readTimeout()
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !isWebSocketUpgrade(req) {
+			w.Header().Set("Upgrade", "websocket")
+			writeError(w, req, http.StatusUpgradeRequired, errNotWebSocket)
+			return
+		}
+
+		// Browsers don't apply same-origin policy to WebSocket, so check it here.
+		if origin := req.Header.Get("Origin"); origin != "" && !isSameOrigin(origin, req.Host) &&
+			(r.cors == nil || !r.cors.isOriginAllowed(origin)) {
+			writeError(w, req, http.StatusForbidden, errOriginNotAllowed)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		if len(r.auth) != 0 {
+			if principal, err := r.auth.Authenticate(req); err == nil {
+				ctx = rpc.WithPrincipal(ctx, principal)
+			}
+		}
+
+		rw, netConn, err := upgradeWebSocket(w, req)
+
+		if err != nil {
+			return
+		}
+
+		conn := &WebSocketConn{
+			id:          newRequestID(),
+			rw:          rw,
+			conn:        netConn,
+			readTimeout: readTimeout,
+		}
+
+		if !r.websockets.Add(conn) {
+			conn.close(wsCloseGoingAway, "server is shutting down")
+			return
+		}
+
+		defer r.websockets.Remove(conn)
+		ctx = context.WithValue(ctx, websocketKey{}, conn)
+		wsConns.Store(conn, struct{}{})
+
+		go conn.ping(ctx, pingInterval)
+
+		wg := &sync.WaitGroup{}
+		sem := make(chan struct{}, maxInFlight)
+		code, reason := wsCloseGoingAway, ""
+
+		for {
+			opcode, message, err := conn.readMessage(maxSize)
+
+			if err != nil {
+				if ce, ok := err.(*wsCloseError); ok {
+					code, reason = ce.code, ce.reason
+				}
+
+				break
+			}
+
+			if opcode != wsOpText {
+				code, reason = wsCloseUnsupported, "only text message is supported"
+				break
+			}
+
+			call := &wsCall{}
+
+			if err := sonic.Unmarshal(message, call); err != nil {
+				conn.reply(nil, encodeFailure(errInvalidWebSocketMsg))
+				continue
+			}
+
+			// Stop reading until there is a free slot.
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				id := strings.Trim(string(call.ID), `"`)
+				conn.reply(call.ID, r.dispatch(ctx, req, id, call.Path, call.Params))
+			}()
+		}
+
+		cancel()
+		wg.Wait()
+		conn.close(code, reason)
+	}
+}
+
+// ping sends a ping every interval until ctx is done.
+func (c *WebSocketConn) ping(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.write(wsOpPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *WebSocketConn) reply(id json.RawMessage, response []byte) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	payload, err := sonic.Marshal(&wsReply{
+		ID:       id,
+		Response: response,
+	})
+
+	if err != nil {
+		return
+	}
+
+	c.write(wsOpText, payload)
+}
+
+// isSameOrigin reports whether origin's host is the same as host.
+func isSameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
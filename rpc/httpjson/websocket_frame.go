@@ -0,0 +1,206 @@
+package httpjson
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// A minimal server side implementation of WebSocket protocol defined in RFC 6455.
+// Extensions and subprotocols are not supported.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// WebSocket close codes.
+const (
+	wsCloseNormal        = 1000
+	wsCloseGoingAway     = 1001
+	wsCloseProtocolError = 1002
+	wsCloseUnsupported   = 1003
+	wsCloseTooLarge      = 1009
+)
+
+// wsCloseError is returned by readMessage when a connection should be closed with code.
+type wsCloseError struct {
+	code   int
+	reason string
+}
+
+func (e *wsCloseError) Error() string {
+	return fmt.Sprintf("httpjson: websocket is closed [code=%v] [reason=%v]", e.code, e.reason)
+}
+
+// isWebSocketUpgrade reports whether r is a valid WebSocket handshake request.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket") &&
+		r.Header.Get("Sec-Websocket-Version") == "13" &&
+		r.Header.Get("Sec-Websocket-Key") != ""
+}
+
+func headerContainsToken(header http.Header, key, token string) bool {
+	for _, v := range header.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept header value.
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte(websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// upgradeWebSocket completes the handshake and takes over the connection.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (rw *bufio.ReadWriter, conn net.Conn, err error) {
+	hijacker, ok := w.(http.Hijacker)
+
+	if !ok {
+		err = fmt.Errorf("httpjson: websocket is not supported by response writer")
+		return
+	}
+
+	if conn, rw, err = hijacker.Hijack(); err != nil {
+		return
+	}
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %v\r\n\r\n",
+		websocketAccept(r.Header.Get("Sec-Websocket-Key")))
+
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		conn = nil
+		return
+	}
+
+	return
+}
+
+// readFrame reads a frame from r.
+// The payload is unmasked. Client frames must be masked.
+func readFrame(r io.Reader, maxSize int64) (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+
+	if header[0]&0x70 != 0 {
+		err = &wsCloseError{code: wsCloseProtocolError, reason: "reserved bits are set"}
+		return
+	}
+
+	if header[1]&0x80 == 0 {
+		err = &wsCloseError{code: wsCloseProtocolError, reason: "client frame is not masked"}
+		return
+	}
+
+	size := int64(header[1] & 0x7F)
+
+	switch size {
+	case 126:
+		var ext [2]byte
+
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return
+		}
+
+		size = int64(binary.BigEndian.Uint16(ext[:]))
+
+	case 127:
+		var ext [8]byte
+
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return
+		}
+
+		size = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= wsOpClose && (size > 125 || !fin) {
+		err = &wsCloseError{code: wsCloseProtocolError, reason: "invalid control frame"}
+		return
+	}
+
+	if size < 0 || size > maxSize {
+		err = &wsCloseError{code: wsCloseTooLarge, reason: "message is too large"}
+		return
+	}
+
+	var mask [4]byte
+
+	if _, err = io.ReadFull(r, mask[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, size)
+
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return
+}
+
+// writeFrame writes a final frame to w without mask.
+func writeFrame(w *bufio.Writer, opcode byte, payload []byte) error {
+	w.WriteByte(0x80 | opcode)
+
+	switch size := len(payload); {
+	case size <= 125:
+		w.WriteByte(byte(size))
+
+	case size <= 0xFFFF:
+		var ext [2]byte
+		binary.BigEndian.PutUint16(ext[:], uint16(size))
+		w.WriteByte(126)
+		w.Write(ext[:])
+
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(size))
+		w.WriteByte(127)
+		w.Write(ext[:])
+	}
+
+	w.Write(payload)
+	return w.Flush()
+}
+
+// closePayload returns the payload of a close frame.
+func closePayload(code int, reason string) []byte {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, reason...)
+}
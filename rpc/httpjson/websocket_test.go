@@ -0,0 +1,284 @@
+package httpjson
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+type testWebSocketSubscribeRequest struct {
+	Topic string `json:"topic"`
+}
+
+type testWebSocketSubscribeResponse struct {
+	Conn string `json:"conn"`
+}
+
+func testWebSocketSubscribe(ctx context.Context, req *testWebSocketSubscribeRequest) (resp *testWebSocketSubscribeResponse, err error) {
+	conn := WebSocketFrom(ctx)
+
+	if conn == nil {
+		err = fmt.Errorf("not a websocket call")
+		return
+	}
+
+	if err = conn.Push(req.Topic, map[string]any{"subscribed": true}); err != nil {
+		return
+	}
+
+	resp = &testWebSocketSubscribeResponse{
+		Conn: conn.ID(),
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testWebSocketSubscribe)
+}
+
+type testWebSocketClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialTestWebSocket(a *assert.A, url string, header http.Header) (*testWebSocketClient, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	a.NilError(err)
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET %v HTTP/1.1\r\nHost: %v\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %v\r\nSec-WebSocket-Version: 13\r\n",
+		defaultWebSocketPath, strings.TrimPrefix(url, "http://"), key)
+	header.Write(conn)
+	fmt.Fprint(conn, "\r\n")
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	a.NilError(err)
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		a.Equal(resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+	}
+
+	return &testWebSocketClient{conn: conn, r: r}, resp
+}
+
+// send writes a masked frame.
+func (c *testWebSocketClient) send(fin bool, opcode byte, payload string) {
+	header := []byte{opcode, 0x80}
+
+	if fin {
+		header[0] |= 0x80
+	}
+
+	if len(payload) <= 125 {
+		header[1] |= byte(len(payload))
+	} else {
+		header[1] |= 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	}
+
+	mask := [4]byte{1, 2, 3, 4}
+	data := []byte(payload)
+
+	for i := range data {
+		data[i] ^= mask[i%4]
+	}
+
+	c.conn.Write(append(append(header, mask[:]...), data...))
+}
+
+func (c *testWebSocketClient) recv(a *assert.A) (opcode byte, payload []byte) {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var header [2]byte
+	_, err := io.ReadFull(c.r, header[:])
+	a.NilError(err)
+	a.Assert(header[1]&0x80 == 0)
+
+	size := int(header[1] & 0x7F)
+
+	if size == 126 {
+		var ext [2]byte
+		_, err = io.ReadFull(c.r, ext[:])
+		a.NilError(err)
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+
+	payload = make([]byte, size)
+	_, err = io.ReadFull(c.r, payload)
+	a.NilError(err)
+	return header[0] & 0x0F, payload
+}
+
+func (c *testWebSocketClient) recvJSON(a *assert.A) map[string]any {
+	opcode, payload := c.recv(a)
+	a.Equal(opcode, byte(wsOpText))
+
+	var msg map[string]any
+	a.NilError(json.Unmarshal(payload, &msg))
+	return msg
+}
+
+func TestWebSocket(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		WebSocket: WebSocketConfig{
+			Enabled:        true,
+			MaxMessageSize: 1024,
+		},
+	}))
+	defer server.Close()
+
+	client, resp := dialTestWebSocket(a, server.URL, http.Header{})
+	defer client.conn.Close()
+	a.Equal(resp.StatusCode, http.StatusSwitchingProtocols)
+
+	// A message in fragments.
+	client.send(false, wsOpText, `{"id": 1, "path": "/test-client-greet", `)
+	client.send(true, wsOpContinuation, `"params": {"name": "Shana"}}`)
+	msg := client.recvJSON(a)
+	a.Equal(msg["id"], float64(1))
+	a.Equal(msg["response"], map[string]any{"data": map[string]any{"greeting": "Hello, Shana"}})
+
+	// Push in handler arrives before reply.
+	client.send(true, wsOpText, `{"id": "sub", "path": "/test-web-socket-subscribe", "params": {"topic": "news"}}`)
+	msg = client.recvJSON(a)
+	a.Equal(msg, map[string]any{"event": "news", "data": map[string]any{"subscribed": true}})
+	msg = client.recvJSON(a)
+	a.Equal(msg["id"], "sub")
+	connID := msg["response"].(map[string]any)["data"].(map[string]any)["conn"]
+	a.NotEqual(connID, "")
+
+	Broadcast("hello", "world")
+	msg = client.recvJSON(a)
+	a.Equal(msg, map[string]any{"event": "hello", "data": "world"})
+
+	client.send(true, wsOpText, `{"id": 2, "path": "/not-found"}`)
+	msg = client.recvJSON(a)
+	a.Equal(msg["response"], map[string]any{"error": errNotFound.Error()})
+
+	client.send(true, wsOpText, `{"id": 3, "path": "/_shana/ws"}`)
+	msg = client.recvJSON(a)
+	a.Equal(msg["response"], map[string]any{"error": errInvalidCall.Error()})
+
+	client.send(true, wsOpText, `not json`)
+	msg = client.recvJSON(a)
+	a.Equal(msg["id"], nil)
+	a.Equal(msg["response"], map[string]any{"error": errInvalidWebSocketMsg.Error()})
+
+	client.send(true, wsOpPing, "ping")
+	opcode, payload := client.recv(a)
+	a.Equal(opcode, byte(wsOpPong))
+	a.Equal(string(payload), "ping")
+
+	client.send(true, wsOpText, `{"id": 4, "params": "`+strings.Repeat("x", 1024)+`"}`)
+	opcode, payload = client.recv(a)
+	a.Equal(opcode, byte(wsOpClose))
+	a.Equal(int(binary.BigEndian.Uint16(payload)), wsCloseTooLarge)
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		WebSocket: WebSocketConfig{
+			Enabled: true,
+		},
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + defaultWebSocketPath)
+	a.NilError(err)
+	resp.Body.Close()
+	a.Equal(resp.StatusCode, http.StatusUpgradeRequired)
+
+	client, resp := dialTestWebSocket(a, server.URL, http.Header{
+		"Origin": []string{"https://evil.example.com"},
+	})
+	client.conn.Close()
+	a.Equal(resp.StatusCode, http.StatusForbidden)
+
+	client, resp = dialTestWebSocket(a, server.URL, http.Header{
+		"Origin": []string{server.URL},
+	})
+	defer client.conn.Close()
+	a.Equal(resp.StatusCode, http.StatusSwitchingProtocols)
+
+	client.send(true, wsOpClose, string(closePayload(wsCloseNormal, "")))
+	opcode, payload := client.recv(a)
+	a.Equal(opcode, byte(wsOpClose))
+	a.Equal(int(binary.BigEndian.Uint16(payload)), wsCloseNormal)
+}
+
+func TestWebSocketTimeout(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		WebSocket: WebSocketConfig{
+			Enabled:      true,
+			PingInterval: 50 * time.Millisecond,
+			ReadTimeout:  200 * time.Millisecond,
+		},
+	}))
+	defer server.Close()
+
+	client, resp := dialTestWebSocket(a, server.URL, http.Header{})
+	defer client.conn.Close()
+	a.Equal(resp.StatusCode, http.StatusSwitchingProtocols)
+
+	opcode, _ := client.recv(a)
+	a.Equal(opcode, byte(wsOpPing))
+
+	// Client never replies, so connection is closed after read timeout.
+	for opcode == wsOpPing {
+		opcode, _ = client.recv(a)
+	}
+
+	a.Equal(opcode, byte(wsOpClose))
+}
+
+func TestWebSocketClose(t *testing.T) {
+	a := assert.New(t)
+	router := NewRouter(&Config{
+		PkgPrefix: testPkgPrefix,
+		WebSocket: WebSocketConfig{
+			Enabled: true,
+		},
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client, resp := dialTestWebSocket(a, server.URL, http.Header{})
+	defer client.conn.Close()
+	a.Equal(resp.StatusCode, http.StatusSwitchingProtocols)
+
+	client.send(true, wsOpText, `{"id": 1, "path": "/test-client-greet", "params": {"name": "Shana"}}`)
+	msg := client.recvJSON(a)
+	a.Equal(msg["id"], float64(1))
+
+	router.Close()
+	opcode, payload := client.recv(a)
+	a.Equal(opcode, byte(wsOpClose))
+	a.Equal(int(binary.BigEndian.Uint16(payload)), wsCloseGoingAway)
+
+	// New connections are refused after close.
+	client, resp = dialTestWebSocket(a, server.URL, http.Header{})
+	defer client.conn.Close()
+	a.Equal(resp.StatusCode, http.StatusSwitchingProtocols)
+	opcode, payload = client.recv(a)
+	a.Equal(opcode, byte(wsOpClose))
+	a.Equal(int(binary.BigEndian.Uint16(payload)), wsCloseGoingAway)
+}
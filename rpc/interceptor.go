@@ -0,0 +1,52 @@
+package rpc
+
+import "context"
+
+// HandlerInfo describes an exported handler.
+type HandlerInfo struct {
+	Package  string // Package name.
+	Name     string // API name.
+	FuncName string // Function name.
+}
+
+// Invoker invokes the next interceptor in the chain or the handler itself.
+type Invoker func(ctx context.Context, req any) (resp any, err error)
+
+// Interceptor intercepts calls to exported handlers.
+//
+// The req is the pointer to the request struct, e.g. *MyRequest.
+// An interceptor must call next to continue the call,
+// or it can return a response or an error directly to short-circuit the call.
+// The resp must be either nil or the pointer to the response struct of the handler.
+type Interceptor func(ctx context.Context, info *HandlerInfo, req any, next Invoker) (resp any, err error)
+
+var globalInterceptors []Interceptor
+
+// Use adds interceptors to all exported handlers.
+//
+// Interceptors are called in the order they are added.
+// All interceptors added by Use run before any interceptor set by WithInterceptors.
+// The request validator and initer always run after all interceptors.
+//
+// Use is not goroutine-safe. It should be called in `init` or before the server starts.
+func Use(interceptors ...Interceptor) {
+	for _, interceptor := range interceptors {
+		if interceptor == nil {
+			continue
+		}
+
+		globalInterceptors = append(globalInterceptors, interceptor)
+	}
+}
+
+// intercept calls interceptors one by one and finally calls the handler.
+func intercept(ctx context.Context, info *HandlerInfo, req any, interceptors []Interceptor, handler Invoker) (resp any, err error) {
+	if len(interceptors) == 0 {
+		return handler(ctx, req)
+	}
+
+	next := func(ctx context.Context, req any) (resp any, err error) {
+		return intercept(ctx, info, req, interceptors[1:], handler)
+	}
+	return interceptors[0](ctx, info, req, next)
+}
@@ -0,0 +1,114 @@
+package jsonrpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc/httpjson"
+)
+
+const (
+	defaultPort           = 9697
+	defaultPath           = "/rpc"
+	defaultMaxBatchSize   = 20
+	defaultMaxConcurrency = 16
+	defaultIdleTimeout    = 5 * time.Minute
+)
+
+// Config for JSON-RPC server.
+type Config struct {
+	IP        string `shana:"ip"`       // The IP to bind. If it's not set, all IPs are bound.
+	Port      int    `shana:"port"`     // The HTTP port to listen.
+	Path      string `shana:"path"`     // The HTTP path of the endpoint. Default is "/rpc".
+	TCPPort   int    `shana:"tcp_port"` // The raw TCP port to listen with newline framing. TCP is disabled if it's 0.
+	PkgPrefix string `shana:"-"`        // Filter all exported handlers by package prefix.
+
+	// Authenticators in the same way as httpjson.
+	// If there is any authenticator, handlers not exported with rpc.Public() require an authenticated request.
+	Auth      httpjson.AuthConfig    `shana:"auth"`
+	RateLimit httpjson.RateLimitRule `shana:"rate_limit"` // The rate limit of every method.
+
+	MaxBatchSize   int           `shana:"max_batch_size"`  // Max requests in a batch. Default is 20.
+	MaxConcurrency int           `shana:"max_concurrency"` // Max concurrent requests in a TCP connection. Default is 16.
+	IdleTimeout    time.Duration `shana:"idle_timeout"`    // How long a TCP connection can be idle. Default is 5m.
+}
+
+// Validate validates the config.
+func (c *Config) Validate(ctx context.Context) {
+	if c.IP != "" {
+		if ip := net.ParseIP(c.IP); ip == nil {
+			errors.Throwf("jsonrpc: invalid IP in config [ip=%v]", c.IP)
+			return
+		}
+	}
+
+	if c.Port < 0 || c.Port > 65535 {
+		errors.Throwf("jsonrpc: invalid port in config [port=%v]", c.Port)
+		return
+	}
+
+	if c.TCPPort < 0 || c.TCPPort > 65535 {
+		errors.Throwf("jsonrpc: invalid TCP port in config [tcp_port=%v]", c.TCPPort)
+		return
+	}
+
+	if c.Path != "" && c.Path[0] != '/' {
+		errors.Throwf("jsonrpc: path must start with '/' [path=%v]", c.Path)
+		return
+	}
+
+	if c.MaxBatchSize < 0 {
+		errors.Throwf("jsonrpc: invalid max batch size [max_batch_size=%v]", c.MaxBatchSize)
+		return
+	}
+
+	if c.MaxConcurrency < 0 {
+		errors.Throwf("jsonrpc: invalid max concurrency [max_concurrency=%v]", c.MaxConcurrency)
+		return
+	}
+
+	if c.IdleTimeout < 0 {
+		errors.Throwf("jsonrpc: invalid idle timeout [idle_timeout=%v]", c.IdleTimeout)
+		return
+	}
+
+	c.Auth.Validate(ctx)
+	c.RateLimit.Validate(ctx)
+}
+
+// Init initializes the config and fills zero values with defaults.
+func (c *Config) Init(ctx context.Context) {
+	if c.Port == 0 {
+		c.Port = defaultPort
+	}
+
+	if c.Path == "" {
+		c.Path = defaultPath
+	}
+}
+
+func (c *Config) maxBatchSize() int {
+	if c.MaxBatchSize == 0 {
+		return defaultMaxBatchSize
+	}
+
+	return c.MaxBatchSize
+}
+
+func (c *Config) maxConcurrency() int {
+	if c.MaxConcurrency == 0 {
+		return defaultMaxConcurrency
+	}
+
+	return c.MaxConcurrency
+}
+
+func (c *Config) idleTimeout() time.Duration {
+	if c.IdleTimeout == 0 {
+		return defaultIdleTimeout
+	}
+
+	return c.IdleTimeout
+}
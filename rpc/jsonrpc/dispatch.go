@@ -0,0 +1,416 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-shana/core/errors"
+	irpc "github.com/go-shana/core/internal/rpc"
+	"github.com/go-shana/core/rpc"
+	"github.com/go-shana/core/rpc/httpjson"
+)
+
+const version = "2.0"
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000 // Errors without error code returned by handlers.
+	CodeUnauthorized   = -32001 // The method requires an authenticated request.
+	CodeRateLimited    = -32002 // The request exceeds the rate limit of the method.
+)
+
+// Request is a JSON-RPC 2.0 request.
+// It's a notification if ID is absent.
+type Request struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response.
+type Response struct {
+	Version string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+var nullID = json.RawMessage("null")
+
+// method is an exported handler callable by JSON-RPC.
+type method struct {
+	handler *irpc.Handler
+	reqType reflect.Type
+	info    *rpc.HandlerInfo
+	limiter *httpjson.RateLimiter
+}
+
+// dispatcher dispatches JSON-RPC requests to exported handlers.
+type dispatcher struct {
+	methods      map[string]*method
+	auth         httpjson.Authenticator
+	maxBatchSize int
+}
+
+// transport contains transport information of a JSON-RPC request.
+type transport struct {
+	// The HTTP request carrying JSON-RPC requests.
+	// It's synthesized for a TCP connection with remote address only.
+	request *http.Request
+}
+
+func newDispatcher(config *Config) (d *dispatcher, err error) {
+	defer errors.Handle(&err)
+
+	pkgPrefix := config.PkgPrefix
+	d = &dispatcher{
+		methods:      map[string]*method{},
+		auth:         errors.Check1(httpjson.NewAuthenticator(&config.Auth)),
+		maxBatchSize: config.maxBatchSize(),
+	}
+
+	for _, handler := range irpc.DefaultRegistry().Handlers(pkgPrefix) {
+		// Streaming handlers cannot be called in JSON-RPC.
+		if handler.StreamItem != nil {
+			continue
+		}
+
+		reqType := handler.Func.Type().In(1).Elem()
+		sonic.Pretouch(reqType)
+
+		m := &method{
+			handler: handler,
+			reqType: reqType,
+			info: &rpc.HandlerInfo{
+				Package:  handler.Package,
+				Name:     handler.Name,
+				FuncName: handler.FuncName,
+			},
+		}
+
+		if config.RateLimit.Rate > 0 {
+			m.limiter = httpjson.NewRateLimiter(&config.RateLimit)
+		}
+
+		d.methods[methodName(pkgPrefix, handler)] = m
+	}
+
+	return
+}
+
+// methodName returns JSON-RPC method name of handler, e.g. "pkg/path.method-name".
+// The package path is relative to pkgPrefix.
+func methodName(pkgPrefix string, handler *irpc.Handler) string {
+	pkg := strings.Trim(handler.Package[len(pkgPrefix):], "/")
+
+	if pkg == "" {
+		return handler.Name
+	}
+
+	return pkg + "." + handler.Name
+}
+
+// Methods returns all method names in order.
+func (d *dispatcher) Methods() []string {
+	names := make([]string, 0, len(d.methods))
+
+	for name := range d.methods {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Authenticate returns the transport of r with the principal authenticated by r.
+// If r is not authenticated by any authenticator, only public methods can be called.
+func (d *dispatcher) Authenticate(r *http.Request) *transport {
+	if d.auth != nil {
+		if principal, err := d.auth.Authenticate(r); err == nil {
+			r = r.WithContext(rpc.WithPrincipal(r.Context(), principal))
+		}
+	}
+
+	return &transport{
+		request: r,
+	}
+}
+
+// Dispatch handles a single request or a batch in data.
+// It returns nil if there is nothing to respond, e.g. data only contains notifications.
+func (d *dispatcher) Dispatch(data []byte, t *transport) []byte {
+	ctx := t.request.Context()
+	data = bytes.TrimSpace(data)
+
+	if len(data) == 0 || data[0] != '[' {
+		resp := d.dispatchRaw(ctx, data, t)
+
+		if resp == nil {
+			return nil
+		}
+
+		return encodeResponse(resp)
+	}
+
+	var batch []json.RawMessage
+
+	if err := sonic.Unmarshal(data, &batch); err != nil {
+		return encodeResponse(errorResponse(nullID, CodeParseError, "Parse error", nil))
+	}
+
+	if len(batch) == 0 {
+		return encodeResponse(errorResponse(nullID, CodeInvalidRequest, "Invalid Request", nil))
+	}
+
+	if len(batch) > d.maxBatchSize {
+		return encodeResponse(errorResponse(nullID, CodeInvalidRequest, "Invalid Request", "too many requests in batch"))
+	}
+
+	responses := make([]*Response, len(batch))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(batch))
+
+	for i := range batch {
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = d.dispatchRaw(ctx, batch[i], t)
+		}(i)
+	}
+
+	wg.Wait()
+
+	results := make([]*Response, 0, len(responses))
+
+	for _, resp := range responses {
+		if resp != nil {
+			results = append(results, resp)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	return encodeResponse(results)
+}
+
+func (d *dispatcher) dispatchRaw(ctx context.Context, data []byte, t *transport) *Response {
+	req := &Request{}
+
+	if err := sonic.Unmarshal(data, req); err != nil {
+		if len(data) != 0 && data[0] == '{' {
+			return errorResponse(nullID, CodeParseError, "Parse error", nil)
+		}
+
+		return errorResponse(nullID, CodeInvalidRequest, "Invalid Request", nil)
+	}
+
+	return d.dispatch(ctx, req, t)
+}
+
+// dispatch calls the method in req.
+// It returns nil if req is a notification.
+func (d *dispatcher) dispatch(ctx context.Context, req *Request, t *transport) *Response {
+	notification := len(req.ID) == 0
+	id := req.ID
+
+	if notification {
+		id = nullID
+	}
+
+	// Invalid requests are always responded even if they don't have ID.
+	if req.Version != version || req.Method == "" || !isValidID(id) {
+		return errorResponse(nullID, CodeInvalidRequest, "Invalid Request", nil)
+	}
+
+	resp := d.invoke(ctx, id, req, t)
+
+	if notification {
+		return nil
+	}
+
+	return resp
+}
+
+// invoke calls the method in req and returns the response with id.
+func (d *dispatcher) invoke(ctx context.Context, id json.RawMessage, req *Request, t *transport) *Response {
+	m := d.methods[req.Method]
+
+	if m == nil {
+		return errorResponse(id, CodeMethodNotFound, "Method not found", nil)
+	}
+
+	if d.auth != nil && !m.handler.Public && rpc.PrincipalFrom(ctx) == nil {
+		return errorResponse(id, CodeUnauthorized, "Unauthorized", nil)
+	}
+
+	if m.limiter != nil {
+		if ok, retryAfter := m.limiter.Allow(t.request, time.Now()); !ok {
+			return errorResponse(id, CodeRateLimited, "Too many requests", map[string]any{
+				"retryAfter": retryAfter.Seconds(),
+			})
+		}
+	}
+
+	reqVal := reflect.New(m.reqType)
+
+	if params := bytes.TrimSpace(req.Params); len(params) != 0 && string(params) != "null" {
+		if params[0] != '{' {
+			return errorResponse(id, CodeInvalidParams, "Invalid params", "params must be an object")
+		}
+
+		if err := sonic.Unmarshal(params, reqVal.Interface()); err != nil {
+			return errorResponse(id, CodeInvalidParams, "Invalid params", err.Error())
+		}
+	}
+
+	ctx = rpc.WithRequestInfo(ctx, &rpc.RequestInfo{
+		ID:         string(bytes.Trim(id, `"`)),
+		RemoteAddr: t.request.RemoteAddr,
+		Method:     t.request.Method,
+		Header:     t.request.Header,
+		Handler:    m.info,
+	})
+
+	result, err := call(ctx, m, reqVal)
+
+	if err != nil {
+		return &Response{
+			Version: version,
+			Error:   newError(err),
+			ID:      id,
+		}
+	}
+
+	return &Response{
+		Version: version,
+		Result:  result,
+		ID:      id,
+	}
+}
+
+func call(ctx context.Context, m *method, reqVal reflect.Value) (result any, err error) {
+	defer errors.Handle(&err)
+
+	ret := m.handler.Func.Call([]reflect.Value{reflect.ValueOf(ctx), reqVal})
+	errors.Assert(len(ret) == 2)
+
+	if errVal := ret[1]; errVal.IsValid() && !errVal.IsNil() {
+		err = errVal.Interface().(error)
+		return
+	}
+
+	if respVal := ret[0]; respVal.IsValid() && !respVal.IsNil() {
+		result = respVal.Interface()
+	} else {
+		// JSON-RPC requires result member on success.
+		result = struct{}{}
+	}
+
+	return
+}
+
+// isValidID reports whether id is a string, a number or null.
+func isValidID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return false
+	}
+
+	switch c := id[0]; {
+	case c == '"', c == '-', c >= '0' && c <= '9':
+		return true
+	default:
+		return string(id) == "null"
+	}
+}
+
+// newError converts err to an Error.
+//
+// If the key error has an int code, e.g. errors.ErrorCode[int], the code is used as is.
+// Codes in other types are set in data.
+// Errors without code use CodeServerError, except invalid requests which use CodeInvalidParams
+// and panics which use CodeInternalError.
+func newError(err error) *Error {
+	key := err
+
+	if he, ok := err.(errors.HandlerError); ok {
+		key = he.KeyError()
+	}
+
+	e := &Error{
+		Code:    CodeServerError,
+		Message: key.Error(),
+	}
+
+	if codeFunc := reflect.ValueOf(key).MethodByName("Code"); codeFunc.IsValid() {
+		if t := codeFunc.Type(); t.NumIn() == 0 && t.NumOut() == 1 {
+			code := codeFunc.Call(nil)[0]
+
+			switch code.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				e.Code = int(code.Int())
+			default:
+				e.Data = map[string]any{"code": code.Interface()}
+			}
+
+			return e
+		}
+	}
+
+	if errors.Is(err, rpc.ErrInvalidRequest) {
+		e.Code = CodeInvalidParams
+		return e
+	}
+
+	var pe errors.PanicError
+
+	if errors.As(err, &pe) {
+		e.Code = CodeInternalError
+	}
+
+	return e
+}
+
+func errorResponse(id json.RawMessage, code int, msg string, data any) *Response {
+	return &Response{
+		Version: version,
+		Error: &Error{
+			Code:    code,
+			Message: msg,
+			Data:    data,
+		},
+		ID: id,
+	}
+}
+
+func encodeResponse(v any) []byte {
+	data, err := sonic.Marshal(v)
+
+	if err != nil {
+		data, _ = sonic.Marshal(errorResponse(nullID, CodeInternalError, "Internal error", fmt.Sprint(err)))
+	}
+
+	return data
+}
@@ -0,0 +1,281 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+	"github.com/go-shana/core/rpc/httpjson"
+	"github.com/huandu/go-assert"
+)
+
+const testPkgPrefix = "github.com/go-shana/core/rpc/jsonrpc"
+
+type testEchoRequest struct {
+	Message string `json:"message"`
+}
+
+type testEchoResponse struct {
+	Message string `json:"message"`
+	ID      string `json:"id"`
+}
+
+var (
+	errTestEmpty    = errors.NewErrorCode(1001, "message is required")
+	errTestNotFound = errors.NewErrorCode("not_found", "message is not found")
+)
+
+func testEcho(ctx context.Context, req *testEchoRequest) (resp *testEchoResponse, err error) {
+	switch req.Message {
+	case "":
+		err = errTestEmpty
+		return
+	case "missing":
+		err = errTestNotFound
+		return
+	}
+
+	resp = &testEchoResponse{
+		Message: req.Message,
+		ID:      rpc.RequestInfoFrom(ctx).ID,
+	}
+	return
+}
+
+func testWhoAmI(ctx context.Context, req *testEchoRequest) (resp *testEchoResponse, err error) {
+	resp = &testEchoResponse{
+		Message: rpc.PrincipalFrom(ctx).Subject,
+	}
+	return
+}
+
+type testValidateRequest struct {
+	Name string `json:"name"`
+}
+
+func (req *testValidateRequest) Validate(ctx context.Context) {
+	if req.Name == "" {
+		errors.Throw(errTestNoName)
+	}
+}
+
+var errTestNoName = errors.New("name is required")
+
+func testValidate(ctx context.Context, req *testValidateRequest) (resp *testEchoResponse, err error) {
+	resp = &testEchoResponse{
+		Message: req.Name,
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testEcho, rpc.Public())
+	rpc.Export(testWhoAmI)
+	rpc.Export(testValidate, rpc.Public())
+}
+
+func TestMethodName(t *testing.T) {
+	a := assert.New(t)
+	d, err := newDispatcher(&Config{PkgPrefix: testPkgPrefix})
+	a.NilError(err)
+	a.Equal(d.Methods(), []string{"test-echo", "test-validate", "test-who-am-i"})
+
+	d, err = newDispatcher(&Config{PkgPrefix: "github.com/go-shana/core/rpc"})
+	a.NilError(err)
+	a.Assert(d.methods["jsonrpc.test-echo"] != nil)
+}
+
+func TestHTTP(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(NewHandler(&Config{PkgPrefix: testPkgPrefix}))
+	defer server.Close()
+
+	post := func(body string) (int, string) {
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		a.NilError(err)
+		return resp.StatusCode, string(data)
+	}
+
+	status, body := post(`{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "hi"}, "id": 1}`)
+	a.Equal(status, http.StatusOK)
+	a.Equal(body, `{"jsonrpc":"2.0","result":{"message":"hi","id":"1"},"id":1}`)
+
+	_, body = post(`{"jsonrpc": "2.0", "method": "test-echo", "params": {}, "id": "a"}`)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":1001,"message":"message is required"},"id":"a"}`)
+
+	_, body = post(`{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "missing"}, "id": 2}`)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"message is not found","data":{"code":"not_found"}},"id":2}`)
+
+	_, body = post(`{"jsonrpc": "2.0", "method": "not-found", "id": 3}`)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":3}`)
+
+	_, body = post(`{"jsonrpc": "2.0", "method": "test-echo", "params": [1], "id": 4}`)
+	a.Assert(strings.Contains(body, `"code":-32602`))
+
+	_, body = post(`{"jsonrpc": "2.0", "method": "test-validate", "params": {}, "id": 5}`)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"name is required"},"id":5}`)
+
+	status, body = post(`{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "` + strings.Repeat("x", maxRequestSize) + `"}, "id": 6}`)
+	a.Equal(status, http.StatusRequestEntityTooLarge)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"request too large"},"id":null}`)
+
+	_, body = post(`{"jsonrpc": "2.0", "method"`)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`)
+
+	_, body = post(`[]`)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`)
+
+	// Notifications are not responded.
+	status, body = post(`{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "hi"}}`)
+	a.Equal(status, http.StatusNoContent)
+	a.Equal(body, "")
+
+	status, body = post(`[
+		{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "a"}, "id": 1},
+		{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "b"}},
+		1,
+		{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "c"}, "id": 3}
+	]`)
+	a.Equal(status, http.StatusOK)
+
+	var results []map[string]any
+	a.NilError(json.Unmarshal([]byte(body), &results))
+	a.Equal(len(results), 3)
+	a.Equal(results[0]["result"].(map[string]any)["message"], "a")
+	a.Equal(results[1]["error"].(map[string]any)["code"], float64(CodeInvalidRequest))
+	a.Equal(results[2]["result"].(map[string]any)["message"], "c")
+}
+
+func TestTCP(t *testing.T) {
+	a := assert.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	a.NilError(err)
+
+	config := &Config{
+		PkgPrefix:   testPkgPrefix,
+		IdleTimeout: 100 * time.Millisecond,
+	}
+	d, err := newDispatcher(config)
+	a.NilError(err)
+	ts := newTCPServer(listener.Addr().String(), d, config)
+	done := make(chan error)
+	go func() {
+		done <- ts.serve(listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	a.NilError(err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(`{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "notify"}}` + "\n" +
+		`{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "tcp"}, "id": 1}` + "\n"))
+	a.NilError(err)
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	a.NilError(err)
+	a.Equal(line, `{"jsonrpc":"2.0","result":{"message":"tcp","id":"1"},"id":1}`+"\n")
+
+	// Idle connection is closed by server.
+	_, err = reader.ReadString('\n')
+	a.Equal(err, io.EOF)
+
+	ts.Close()
+	a.NilError(<-done)
+}
+
+func TestAuthAndLimits(t *testing.T) {
+	a := assert.New(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.yaml")
+	a.NilError(os.WriteFile(keysFile, []byte("svc-a: secret-a\n"), 0600))
+	server := httptest.NewServer(NewHandler(&Config{
+		PkgPrefix: testPkgPrefix,
+		Auth: httpjson.AuthConfig{
+			HMAC: httpjson.HMACConfig{
+				KeysFile: keysFile,
+			},
+		},
+		RateLimit: httpjson.RateLimitRule{
+			Rate:  0.001,
+			Burst: 2,
+			Key:   "principal",
+		},
+		MaxBatchSize: 2,
+	}))
+	defer server.Close()
+
+	post := func(body string, signed bool) string {
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		a.NilError(err)
+
+		if signed {
+			ts := time.Now().Unix()
+			bodyHash := sha256.Sum256([]byte(body))
+			sig := httpjson.SignRequest([]byte("secret-a"), req.Method, req.URL.RequestURI(), ts, bodyHash[:])
+			req.Header.Set("Authorization", fmt.Sprintf("HMAC-SHA256 Credential=svc-a, Timestamp=%v, Signature=%v", ts, hex.EncodeToString(sig)))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		a.NilError(err)
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		a.NilError(err)
+		return string(data)
+	}
+
+	body := post(`{"jsonrpc": "2.0", "method": "test-who-am-i", "id": 1}`, false)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32001,"message":"Unauthorized"},"id":1}`)
+
+	body = post(`{"jsonrpc": "2.0", "method": "test-echo", "params": {"message": "public"}, "id": 2}`, false)
+	a.Assert(strings.Contains(body, `"result":{"message":"public"`))
+
+	body = post(`{"jsonrpc": "2.0", "method": "test-who-am-i", "id": 3}`, true)
+	a.Equal(body, `{"jsonrpc":"2.0","result":{"message":"svc-a","id":""},"id":3}`)
+
+	body = post(`[
+		{"jsonrpc": "2.0", "method": "test-who-am-i", "id": 4},
+		{"jsonrpc": "2.0", "method": "test-who-am-i", "id": 5},
+		{"jsonrpc": "2.0", "method": "test-who-am-i", "id": 6}
+	]`, true)
+	a.Equal(body, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":"too many requests in batch"},"id":null}`)
+
+	body = post(`[
+		{"jsonrpc": "2.0", "method": "test-who-am-i", "id": 7},
+		{"jsonrpc": "2.0", "method": "test-who-am-i", "id": 8}
+	]`, true)
+
+	var results []map[string]any
+	a.NilError(json.Unmarshal([]byte(body), &results))
+	a.Equal(len(results), 2)
+
+	// One of them exceeds the rate limit as burst is 2.
+	codes := 0
+
+	for _, result := range results {
+		if e, ok := result["error"].(map[string]any); ok {
+			a.Equal(e["code"], float64(CodeRateLimited))
+			codes++
+		}
+	}
+
+	a.Equal(codes, 1)
+}
@@ -0,0 +1,156 @@
+// Package jsonrpc provides a JSON-RPC 2.0 server for all exported handlers.
+//
+// The method name of a handler is its package path relative to PkgPrefix and its name joined by ".",
+// e.g. "user/profile.get-info". Handlers in the package at PkgPrefix use their names as method names.
+// Params must be a JSON object which is decoded to the request struct.
+//
+// Requests are authenticated and rate limited with the same authenticators and rules as httpjson.
+// If there is any authenticator, handlers not exported with rpc.Public() require an authenticated request.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/rpc"
+)
+
+const maxRequestSize = 4 << 20
+
+// Server is a JSON-RPC 2.0 server over HTTP and, optionally, raw TCP.
+type Server struct {
+	config     *Config
+	dispatcher *dispatcher
+	server     *http.Server
+	tcp        *tcpServer
+}
+
+var _ rpc.Server = new(Server)
+
+// NewServer creates a new JSON-RPC server.
+// It panics if authenticators in config cannot be created.
+func NewServer(config *Config) *Server {
+	d := errors.Check1(newDispatcher(config))
+	mux := http.NewServeMux()
+	mux.Handle(config.Path, &httpHandler{dispatcher: d})
+
+	for _, name := range d.Methods() {
+		// TODO: use logger instead of fmt.
+		fmt.Println("jsonrpc:", name)
+	}
+
+	s := &Server{
+		config:     config,
+		dispatcher: d,
+		server: &http.Server{
+			Addr:    net.JoinHostPort(config.IP, strconv.Itoa(config.Port)),
+			Handler: mux,
+		},
+	}
+
+	if config.TCPPort != 0 {
+		s.tcp = newTCPServer(net.JoinHostPort(config.IP, strconv.Itoa(config.TCPPort)), d, config)
+	}
+
+	return s
+}
+
+// NewHandler creates a http.Handler serving JSON-RPC requests at any path.
+// It panics if authenticators in config cannot be created.
+func NewHandler(config *Config) http.Handler {
+	return &httpHandler{
+		dispatcher: errors.Check1(newDispatcher(config)),
+	}
+}
+
+// Serve starts the server.
+func (s *Server) Serve(ctx context.Context) error {
+	// TODO: use logger instead of fmt.
+	fmt.Printf("JSON-RPC server is starting at address %v\n", s.server.Addr)
+
+	errs := make(chan error, 2)
+	wg := &sync.WaitGroup{}
+
+	if s.tcp != nil {
+		fmt.Printf("JSON-RPC TCP server is starting at address %v\n", s.tcp.addr)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.tcp.Serve(); err != nil {
+				errs <- err
+				s.server.Close()
+			}
+		}()
+	}
+
+	if err := s.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		errs <- err
+
+		if s.tcp != nil {
+			s.tcp.Close()
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// Shutdown stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.tcp != nil {
+		s.tcp.Close()
+	}
+
+	return s.server.Shutdown(ctx)
+}
+
+type httpHandler struct {
+	dispatcher *dispatcher
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+
+		if !errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write(encodeResponse(errorResponse(nullID, CodeInvalidRequest, "Invalid Request", "request too large")))
+		return
+	}
+
+	// Authenticators may verify the body, e.g. HMAC signature.
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	resp := h.dispatcher.Dispatch(data, h.dispatcher.Authenticate(r))
+
+	// Nothing to respond for notifications.
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(resp)
+}
@@ -0,0 +1,186 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// tcpServer serves JSON-RPC over raw TCP.
+// Every request or batch is a line of JSON, so is every response.
+// Requests in a connection are handled concurrently and responses may be out of order.
+// A connection is closed if it doesn't send any request in idle timeout.
+type tcpServer struct {
+	addr           string
+	dispatcher     *dispatcher
+	maxConcurrency int
+	idleTimeout    time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func newTCPServer(addr string, d *dispatcher, config *Config) *tcpServer {
+	return &tcpServer{
+		addr:           addr,
+		dispatcher:     d,
+		maxConcurrency: config.maxConcurrency(),
+		idleTimeout:    config.idleTimeout(),
+		conns:          map[net.Conn]struct{}{},
+	}
+}
+
+// Serve accepts connections until Close is called.
+func (ts *tcpServer) Serve() error {
+	listener, err := net.Listen("tcp", ts.addr)
+
+	if err != nil {
+		return err
+	}
+
+	return ts.serve(listener)
+}
+
+func (ts *tcpServer) serve(listener net.Listener) error {
+	ts.mu.Lock()
+
+	if ts.closed {
+		ts.mu.Unlock()
+		listener.Close()
+		return nil
+	}
+
+	ts.listener = listener
+	ts.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			ts.mu.Lock()
+			closed := ts.closed
+			ts.mu.Unlock()
+
+			if closed || errors.Is(err, net.ErrClosed) {
+				ts.wg.Wait()
+				return nil
+			}
+
+			return err
+		}
+
+		if !ts.track(conn) {
+			conn.Close()
+			continue
+		}
+
+		ts.wg.Add(1)
+		go ts.serveConn(conn)
+	}
+}
+
+func (ts *tcpServer) track(conn net.Conn) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closed {
+		return false
+	}
+
+	ts.conns[conn] = struct{}{}
+	return true
+}
+
+func (ts *tcpServer) serveConn(conn net.Conn) {
+	defer ts.wg.Done()
+	defer func() {
+		ts.mu.Lock()
+		delete(ts.conns, conn)
+		ts.mu.Unlock()
+		conn.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// There is no credential in TCP connection except the remote address.
+	t := ts.dispatcher.Authenticate((&http.Request{
+		URL:        &url.URL{},
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		RemoteAddr: conn.RemoteAddr().String(),
+	}).WithContext(ctx))
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxRequestSize)
+
+	writeMu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+
+	// Limit concurrent requests. Reading is paused when it's full.
+	sem := make(chan struct{}, ts.maxConcurrency)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(ts.idleTimeout))
+
+		if !scanner.Scan() {
+			break
+		}
+
+		line := append([]byte(nil), scanner.Bytes()...)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			resp := ts.dispatcher.Dispatch(line, t)
+
+			if resp == nil {
+				return
+			}
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+
+			// Don't block forever on a client which doesn't read responses.
+			conn.SetWriteDeadline(time.Now().Add(ts.idleTimeout))
+			conn.Write(append(resp, '\n'))
+		}()
+	}
+}
+
+// Close closes the listener and all connections.
+func (ts *tcpServer) Close() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closed {
+		return
+	}
+
+	ts.closed = true
+
+	if ts.listener != nil {
+		ts.listener.Close()
+	}
+
+	for conn := range ts.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"strings"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/internal/rpc"
+)
+
+// Option customizes an exported handler.
+type Option func(opts *options)
+
+type options struct {
+	interceptors []Interceptor
+	http         rpc.HTTPRoute
+	public       bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	return o
+}
+
+// WithInterceptors adds interceptors to an exported handler.
+// These interceptors run after all interceptors added by Use in the order they are given.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(opts *options) {
+		for _, interceptor := range interceptors {
+			if interceptor == nil {
+				continue
+			}
+
+			opts.interceptors = append(opts.interceptors, interceptor)
+		}
+	}
+}
+
+// HTTP sets the HTTP method and the path template of an exported handler.
+//
+// The path is an absolute path which may contain parameters in braces, e.g. "/users/{id}".
+// Parameter values are decoded into request struct fields whose names are the same as parameter names.
+// If path is empty, the path derived from package and handler name is used.
+//
+// By default, a handler is served at the derived path with both GET and POST methods.
+func HTTP(method, path string) Option {
+	method = strings.ToUpper(method)
+
+	if method == "" {
+		errors.Throwf("rpc: HTTP method is required [path=%v]", path)
+	}
+
+	if path != "" && path[0] != '/' {
+		errors.Throwf("rpc: HTTP path must start with '/' [path=%v]", path)
+	}
+
+	return func(opts *options) {
+		opts.http.Method = method
+		opts.http.Path = path
+	}
+}
+
+// HTTPStatus sets the HTTP status code of successful responses of an exported handler.
+// The status must be in range [100, 599]. It doesn't apply to streaming handlers.
+func HTTPStatus(status int) Option {
+	if status < 100 || status > 599 {
+		errors.Throwf("rpc: invalid HTTP status [status=%v]", status)
+	}
+
+	return func(opts *options) {
+		opts.http.Status = status
+	}
+}
+
+// Public marks an exported handler as a public endpoint which doesn't require authentication.
+func Public() Option {
+	return func(opts *options) {
+		opts.public = true
+	}
+}
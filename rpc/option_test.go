@@ -0,0 +1,26 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/go-shana/core/errors"
+	"github.com/huandu/go-assert"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	a := assert.New(t)
+
+	for _, status := range []int{0, 99, 600, 999} {
+		err := func() (err error) {
+			defer errors.Handle(&err)
+			HTTPStatus(status)
+			return
+		}()
+		a.Use(&status)
+		a.NonNilError(err)
+	}
+
+	opts := &options{}
+	HTTPStatus(599)(opts)
+	a.Equal(opts.http.Status, 599)
+}
@@ -0,0 +1,24 @@
+package rpc
+
+import "context"
+
+// Principal is the authenticated identity of a request.
+type Principal struct {
+	Subject string         // The identity, e.g. user ID in JWT "sub" claim or HMAC key ID.
+	Scheme  string         // The authentication scheme, e.g. "jwt" or "hmac".
+	Claims  map[string]any // All claims of the credential. It may be nil.
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx with principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the Principal in ctx.
+// It returns nil if the request is not authenticated, e.g. the handler is public.
+func PrincipalFrom(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"context"
+	"net/textproto"
+)
+
+// RequestInfo contains transport information of a request.
+// It's set by RPC server before calling any interceptor, validator, initer or handler.
+type RequestInfo struct {
+	ID         string              // Request ID. It's either taken from client or generated by server.
+	RemoteAddr string              // Network address of the client.
+	Method     string              // Transport method, e.g. "GET" or "POST" in HTTP.
+	Header     map[string][]string // Request headers. Keys are in canonical MIME header format.
+	Handler    *HandlerInfo        // The matched handler.
+}
+
+// GetHeader returns the first value associated with the key in Header.
+// The key is case insensitive.
+func (info *RequestInfo) GetHeader(key string) string {
+	if info == nil || info.Header == nil {
+		return ""
+	}
+
+	vs := info.Header[textproto.CanonicalMIMEHeaderKey(key)]
+
+	if len(vs) == 0 {
+		return ""
+	}
+
+	return vs[0]
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo returns a copy of ctx with info.
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom returns the RequestInfo in ctx.
+// It returns nil if there is no RequestInfo in ctx, e.g. the handler is called by Compile in test.
+func RequestInfoFrom(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info
+}
@@ -0,0 +1,194 @@
+// Package rpctest provides an in-process harness to test exported handlers end-to-end.
+//
+// A Server loads config from inline YAML, initializes all configs created by config.New,
+// runs lifecycle callbacks and serves calls through the same pipeline as httpjson server
+// without listening on any port.
+//
+//	func TestGreet(t *testing.T) {
+//	    s := rpctest.New(t, `
+//	greeting:
+//	  prefix: Hello
+//	`, rpctest.PkgPrefix("github.com/my/service"))
+//	    resp := s.Call("/greet", &GreetRequest{Name: "Shana"})
+//
+//	    var data GreetResponse
+//	    resp.Decode(&data)
+//	    ...
+//	}
+//
+// Configs and lifecycle callbacks are global in a process.
+// Every New decodes config data to registered configs again and runs all callbacks again.
+package rpctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-shana/core/initer"
+	"github.com/go-shana/core/internal/config"
+	"github.com/go-shana/core/internal/lifecycle"
+	"github.com/go-shana/core/rpc/httpjson"
+	"github.com/go-shana/core/validator"
+)
+
+// Server serves calls to exported handlers in process.
+type Server struct {
+	t      testing.TB
+	router *httpjson.Router
+}
+
+// Option is an option of New.
+type Option func(opts *options)
+
+type options struct {
+	config *httpjson.Config
+}
+
+// HTTPJSON sets the config of httpjson router used by server.
+// Config.PkgPrefix must be set to serve any handler.
+func HTTPJSON(config *httpjson.Config) Option {
+	return func(opts *options) {
+		opts.config = config
+	}
+}
+
+// PkgPrefix serves all handlers in packages with prefix using default httpjson config.
+func PkgPrefix(prefix string) Option {
+	return func(opts *options) {
+		opts.config = &httpjson.Config{
+			PkgPrefix: prefix,
+		}
+	}
+}
+
+// New creates a new server with config content in YAML.
+// The yaml can be empty if there is no config.
+//
+// OnConnect and OnStart callbacks are called in New.
+// OnShutdown callbacks are called when t finishes.
+// Any error fails t immediately.
+func New(t testing.TB, yaml string, opts ...Option) *Server {
+	t.Helper()
+
+	o := &options{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.config == nil {
+		o.config = &httpjson.Config{}
+	}
+
+	ctx := context.Background()
+	conf := config.New()
+
+	if strings.TrimSpace(yaml) != "" {
+		if err := conf.LoadBytes(ctx, []byte(yaml)); err != nil {
+			t.Fatalf("rpctest: fail to load config [err=%v]", err)
+		}
+	}
+
+	if err := config.DefaultRegistry().Decode(ctx, conf.Data()); err != nil {
+		t.Fatalf("rpctest: fail to decode config [err=%v]", err)
+	}
+
+	if err := validator.Validate(ctx, o.config); err != nil {
+		t.Fatalf("rpctest: invalid httpjson config [err=%v]", err)
+	}
+
+	if err := initer.Init(ctx, o.config); err != nil {
+		t.Fatalf("rpctest: fail to init httpjson config [err=%v]", err)
+	}
+
+	if err := lifecycle.OnConnect.Run(ctx); err != nil {
+		t.Fatalf("rpctest: fail to run connect callbacks [err=%v]", err)
+	}
+
+	if err := lifecycle.OnStart.Run(ctx); err != nil {
+		t.Fatalf("rpctest: fail to run start-up callbacks [err=%v]", err)
+	}
+
+	t.Cleanup(func() {
+		if err := lifecycle.OnShutdown.Run(ctx); err != nil {
+			t.Errorf("rpctest: fail to run shutdown callbacks [err=%v]", err)
+		}
+	})
+
+	return &Server{
+		t:      t,
+		router: httpjson.NewRouter(o.config),
+	}
+}
+
+// Response is the response of a call.
+type Response struct {
+	httpjson.Response
+
+	StatusCode int
+	Header     http.Header
+	Body       []byte // The raw response body.
+}
+
+// Decode decodes the data in response to v.
+func (resp *Response) Decode(v any) error {
+	return json.Unmarshal(resp.Body, &struct {
+		Data any `json:"data"`
+	}{v})
+}
+
+// Call calls the handler at path with req encoded in JSON body.
+// If req is nil, an empty JSON object is sent.
+func (s *Server) Call(path string, req any) *Response {
+	s.t.Helper()
+
+	body := []byte("{}")
+
+	if req != nil {
+		data, err := json.Marshal(req)
+
+		if err != nil {
+			s.t.Fatalf("rpctest: fail to encode request [path=%v] [err=%v]", path, err)
+		}
+
+		body = data
+	}
+
+	r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return s.Do(r)
+}
+
+// Do serves r and decodes the response.
+// It's useful to call handlers with custom method or headers.
+func (s *Server) Do(r *http.Request) *Response {
+	s.t.Helper()
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	result := w.Result()
+	resp := &Response{
+		StatusCode: result.StatusCode,
+		Header:     result.Header,
+		Body:       w.Body.Bytes(),
+	}
+
+	if len(resp.Body) == 0 {
+		return resp
+	}
+
+	if ct := result.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		s.t.Fatalf("rpctest: response is not JSON [path=%v] [content-type=%v]", r.URL.Path, ct)
+	}
+
+	if err := json.Unmarshal(resp.Body, &resp.Response); err != nil {
+		s.t.Fatalf("rpctest: fail to decode response [path=%v] [err=%v]", r.URL.Path, err)
+	}
+
+	return resp
+}
@@ -0,0 +1,101 @@
+package rpctest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-shana/core/config"
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/launcher"
+	"github.com/go-shana/core/rpc"
+	"github.com/huandu/go-assert"
+)
+
+const testPkgPrefix = "github.com/go-shana/core/rpc/rpctest"
+
+type testGreetingConfig struct {
+	Prefix string `shana:"prefix"`
+	Suffix string `shana:"suffix"`
+}
+
+func (c *testGreetingConfig) Init(ctx context.Context) {
+	if c.Suffix == "" {
+		c.Suffix = "!"
+	}
+}
+
+var (
+	testGreeting = config.New[testGreetingConfig]("test.greeting")
+	testStarted  bool
+)
+
+type testGreetRequest struct {
+	Name string `json:"name"`
+}
+
+type testGreetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+var errTestNameRequired = errors.NewErrorCode(1001, "name is required")
+
+func testGreet(ctx context.Context, req *testGreetRequest) (resp *testGreetResponse, err error) {
+	if req.Name == "" {
+		err = errTestNameRequired
+		return
+	}
+
+	resp = &testGreetResponse{
+		Greeting: testGreeting.Prefix + ", " + req.Name + testGreeting.Suffix,
+	}
+	return
+}
+
+func init() {
+	rpc.Export(testGreet)
+	launcher.OnStart(func(ctx context.Context) error {
+		testStarted = true
+		return nil
+	})
+}
+
+func TestServer(t *testing.T) {
+	a := assert.New(t)
+	s := New(t, `
+test:
+  greeting:
+    prefix: Hello
+`, PkgPrefix(testPkgPrefix))
+	a.Assert(testStarted)
+
+	resp := s.Call("/test-greet", &testGreetRequest{Name: "Shana"})
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Equal(resp.Error, "")
+
+	var data testGreetResponse
+	a.NilError(resp.Decode(&data))
+	a.Equal(data.Greeting, "Hello, Shana!")
+
+	resp = s.Call("/test-greet", nil)
+	a.Equal(resp.Code, float64(1001))
+	a.Equal(resp.Message, "name is required")
+
+	resp = s.Do(httptest.NewRequest(http.MethodPut, "/test-greet", nil))
+	a.Equal(resp.StatusCode, http.StatusMethodNotAllowed)
+	a.Equal(resp.Header.Get("Allow"), "GET, HEAD, POST, OPTIONS")
+
+	resp = s.Call("/not-found", nil)
+	a.Equal(resp.StatusCode, http.StatusNotFound)
+	a.NotEqual(resp.Error, "")
+}
+
+func TestServerEmptyConfig(t *testing.T) {
+	a := assert.New(t)
+	s := New(t, "", PkgPrefix(testPkgPrefix))
+
+	resp := s.Call("/test-greet", &testGreetRequest{Name: "Shana"})
+	a.Equal(resp.StatusCode, http.StatusOK)
+	a.Equal(resp.Error, "")
+}
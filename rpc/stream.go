@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-shana/core/errors"
+	"github.com/go-shana/core/internal/rpc"
+	"github.com/huandu/xstrings"
+)
+
+// Stream sends items to client in a streaming handler.
+type Stream[Item any] interface {
+	// Send sends an item to client.
+	// It returns error if client has gone away or the item cannot be sent.
+	Send(item *Item) error
+}
+
+// StreamHandlerFunc is a handler which sends any number of items to client through stream.
+type StreamHandlerFunc[Request, Item any] func(ctx context.Context, req *Request, stream Stream[Item]) error
+
+type stream[Item any] struct {
+	send rpc.SendFunc
+}
+
+var _ Stream[int] = &stream[int]{}
+
+func (s *stream[Item]) Send(item *Item) error {
+	return s.send(item)
+}
+
+// ExportStream exports a streaming method to RPC.
+// The name of method will be converted to kebab-case.
+func ExportStream[Request, Item any](method StreamHandlerFunc[Request, Item], opts ...Option) {
+	val := reflect.ValueOf(method)
+	info := parseFuncInfo(val)
+	name := xstrings.ToKebabCase(info.Name)
+	exportStream(info.Package, info.Name, name, method, opts)
+}
+
+// ExportStreamName exports a streaming method to RPC with specified name.
+func ExportStreamName[Request, Item any](name string, method StreamHandlerFunc[Request, Item], opts ...Option) {
+	val := reflect.ValueOf(method)
+	info := parseFuncInfo(val)
+	exportStream(info.Package, info.Name, name, method, opts)
+}
+
+func exportStream[Request, Item any](pkg, funcName, name string, method StreamHandlerFunc[Request, Item], opts []Option) {
+	info := &HandlerInfo{
+		Package:  pkg,
+		Name:     name,
+		FuncName: funcName,
+	}
+	o := newOptions(opts)
+	compiled := compileStream(info, method, o)
+	register(&rpc.Handler{
+		Package:    pkg,
+		Name:       name,
+		FuncName:   funcName,
+		Func:       reflect.ValueOf(compiled),
+		StreamItem: reflect.TypeOf((*Item)(nil)).Elem(),
+		HTTP:       o.http,
+		Public:     o.public,
+	})
+}
+
+// CompileStream wraps a streaming method with interceptors, request validator, initer and error handler.
+// The send is called for every item sent by the method.
+//
+// Business code should never call CompileStream unless for test purpose.
+func CompileStream[Request, Item any](method StreamHandlerFunc[Request, Item], opts ...Option) func(ctx context.Context, req *Request, send func(item *Item) error) error {
+	info := parseFuncInfo(reflect.ValueOf(method))
+	compiled := compileStream(&HandlerInfo{
+		Package:  info.Package,
+		Name:     xstrings.ToKebabCase(info.Name),
+		FuncName: info.Name,
+	}, method, newOptions(opts))
+
+	return func(ctx context.Context, req *Request, send func(item *Item) error) error {
+		return compiled(ctx, req, func(item any) error {
+			return send(item.(*Item))
+		})
+	}
+}
+
+func compileStream[Request, Item any](info *HandlerInfo, method StreamHandlerFunc[Request, Item], opts *options) func(ctx context.Context, req *Request, send rpc.SendFunc) error {
+	interceptors := opts.interceptors
+
+	return func(ctx context.Context, req *Request, send rpc.SendFunc) (err error) {
+		defer errors.Handle(&err)
+
+		s := &stream[Item]{
+			send: send,
+		}
+		handler := func(ctx context.Context, req any) (resp any, err error) {
+			r, ok := req.(*Request)
+
+			if !ok {
+				return nil, fmt.Errorf("rpc: invalid request type [expected=%T] [actual=%T]", r, req)
+			}
+
+			checkRequest(ctx, r)
+			return nil, method(ctx, r, s)
+		}
+
+		_, err = intercept(ctx, info, req, globalInterceptors, func(ctx context.Context, req any) (resp any, err error) {
+			return intercept(ctx, info, req, interceptors, handler)
+		})
+		return
+	}
+}